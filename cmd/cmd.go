@@ -3,10 +3,15 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	neturl "net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"sync/atomic"
@@ -14,28 +19,51 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/schollz/progressbar/v3"
+	"github.com/xhad/yes/internal/app"
 	"github.com/xhad/yes/internal/models"
 	cfgPkg "github.com/xhad/yes/pkg/config"
 	"github.com/xhad/yes/pkg/llm"
 	"github.com/xhad/yes/pkg/processor"
 	"github.com/xhad/yes/pkg/scraper"
+	"github.com/xhad/yes/pkg/source"
 	"github.com/xhad/yes/pkg/store"
+	"github.com/xhad/yes/pkg/warc"
 )
 
 type Config struct {
-	BaseURL     string
-	DBUrl       string
-	DocsURL     string
-	Model       string
-	MaxDepth    int
-	ChunkSize   int
-	VectorDim   int
-	TableName   string
-	BatchSize   int
-	RateLimit   float64
-	MaxTokens   int
-	Streaming   bool
-	Temperature float64
+	BaseURL        string
+	DBUrl          string
+	DocsURL        string
+	Model          string
+	MaxDepth       int
+	VectorDim      int
+	TableName      string
+	BatchSize      int
+	RateLimit      float64
+	Workers        int
+	MaxQueueSize   int
+	UserAgent      string
+	IgnoreRobots   bool
+	UseSitemaps    bool
+	TrackingParams []string
+	Retry          scraper.RetryPolicy
+	Scope          scraper.CrawlScope
+	HostRateLimits []scraper.HostRateLimit
+	HTTPProfiles   []scraper.HTTPProfile
+	Checkpoint     string // checkpoint backend: file, postgres or empty
+	CheckpointDir  string
+	SaveInterval   time.Duration // how often checkpoints are saved
+	ArchiveDir     string        // WARC files of every crawl, disabled if empty
+	Extractor      string
+	Extractors     []cfgPkg.ExtractorConfig
+	FSInclude      []string
+	FSExclude      []string
+	MaxTokens      int
+	Streaming      bool
+	Temperature    float64
+
+	// Chunking and normalization, by default and for each source
+	Processor cfgPkg.ProcessorConfig
 }
 
 func main() {
@@ -56,11 +84,22 @@ func parseFlags() Config {
 	flag.StringVar(&config.DocsURL, "docs-url", "", "Documentation URL to scrape")
 	flag.StringVar(&config.Model, "model", "gpt-3.5-turbo", "LLM model to use")
 	flag.IntVar(&config.MaxDepth, "max-depth", 3, "Maximum depth for web scraping")
-	flag.IntVar(&config.ChunkSize, "chunk-size", 1000, "Size of text chunks")
+	flag.IntVar(&config.Processor.ChunkSize, "chunk-size", 512, "Maximum tokens in a text chunk")
+	flag.IntVar(&config.Processor.ChunkOverlap, "chunk-overlap", 64, "Tokens of text repeated between chunks")
+	flag.StringVar(&config.Processor.Chunker, "chunker", "", "Chunking strategy: sentence, fixed, recursive, sentence-window or semantic")
 	flag.IntVar(&config.VectorDim, "vector-dim", 768, "Vector dimension")
 	flag.StringVar(&config.TableName, "table", "documents", "PostgreSQL table name")
 	flag.IntVar(&config.BatchSize, "batch-size", 100, "Batch size for database operations")
 	flag.Float64Var(&config.RateLimit, "rate-limit", 2.0, "Rate limit for web scraping")
+	flag.IntVar(&config.Workers, "workers", 4, "Number of concurrent scraper workers")
+	flag.IntVar(&config.MaxQueueSize, "max-queue-size", 10000, "Maximum number of URLs waiting to be crawled")
+	flag.StringVar(&config.UserAgent, "user-agent", "", "User-Agent for web scraping")
+	flag.BoolVar(&config.IgnoreRobots, "ignore-robots", false, "Ignore robots.txt when scraping")
+	flag.BoolVar(&config.UseSitemaps, "sitemaps", false, "Seed scraping from the site's sitemaps")
+	flag.StringVar(&config.Checkpoint, "checkpoint", "", "Save crawl checkpoints to: file or postgres")
+	flag.StringVar(&config.CheckpointDir, "checkpoint-dir", ".yes/checkpoints", "Directory of file checkpoints")
+	flag.StringVar(&config.ArchiveDir, "archive-dir", "", "Directory to write a WARC archive of every crawl to")
+	flag.StringVar(&config.Extractor, "extractor", "", "Content extractor: selectors or readability")
 	flag.IntVar(&config.MaxTokens, "max-tokens", 2000, "Maximum tokens for LLM response")
 	flag.BoolVar(&config.Streaming, "stream", true, "Enable streaming responses")
 	flag.Float64Var(&config.Temperature, "temperature", 0.8, "Set the LLM Temperature")
@@ -83,7 +122,39 @@ func parseFlags() Config {
 		config.BatchSize = cfg.Database.BatchSize
 		config.MaxDepth = cfg.Scraper.MaxDepth
 		config.RateLimit = cfg.Scraper.RateLimit
-		config.ChunkSize = cfg.Processor.ChunkSize
+		config.Workers = cfg.Scraper.Workers
+		config.MaxQueueSize = cfg.Scraper.MaxQueueSize
+		config.UserAgent = cfg.Scraper.UserAgent
+		config.IgnoreRobots = cfg.Scraper.IgnoreRobots
+		config.UseSitemaps = cfg.Scraper.UseSitemaps
+		config.TrackingParams = cfg.Scraper.TrackingParams
+		config.Retry = scraper.RetryPolicy{
+			MaxAttempts: cfg.Scraper.Retry.MaxAttempts,
+			BaseDelay:   cfg.Scraper.Retry.BaseDelay,
+			MaxDelay:    cfg.Scraper.Retry.MaxDelay,
+		}
+		config.Scope = scraper.CrawlScope{
+			Hosts:        cfg.Scraper.Scope.Hosts,
+			PathPrefixes: cfg.Scraper.Scope.PathPrefixes,
+			Include:      cfg.Scraper.Scope.Include,
+			Exclude:      cfg.Scraper.Scope.Exclude,
+		}
+		for _, limit := range cfg.Scraper.HostRateLimits {
+			config.HostRateLimits = append(config.HostRateLimits, scraper.HostRateLimit{
+				Hosts:     limit.Hosts,
+				RateLimit: limit.RateLimit,
+			})
+		}
+		config.HTTPProfiles = app.BuildHTTPProfiles(cfg.Scraper.HTTPProfiles)
+		config.Checkpoint = cfg.Scraper.Checkpoint.Backend
+		config.CheckpointDir = cfg.Scraper.Checkpoint.Dir
+		config.SaveInterval = cfg.Scraper.Checkpoint.Interval
+		config.ArchiveDir = cfg.Scraper.ArchiveDir
+		config.Extractor = cfg.Scraper.Extractor
+		config.Extractors = cfg.Scraper.Extractors
+		config.FSInclude = cfg.Filesystem.Include
+		config.FSExclude = cfg.Filesystem.Exclude
+		config.Processor = cfg.Processor
 		config.Streaming = cfg.UI.Streaming
 		config.Temperature = cfg.LLM.Temperature
 	}
@@ -91,6 +162,416 @@ func parseFlags() Config {
 	return config
 }
 
+// buildCheckpointStore returns where crawl checkpoints are saved, or nil if
+// checkpoints are disabled.
+func buildCheckpointStore(config Config, vectorStore *store.VectorStore) (scraper.CheckpointStore, error) {
+	switch config.Checkpoint {
+	case "":
+		return nil, nil
+	case "file":
+		return scraper.NewFileCheckpointStore(config.CheckpointDir)
+	case "postgres":
+		return vectorStore, nil
+	default:
+		return nil, fmt.Errorf("unknown checkpoint backend: %s", config.Checkpoint)
+	}
+}
+
+// documentSource is implemented by the local ingestion sources.
+type documentSource interface {
+	Stream(ctx context.Context) (<-chan models.Document, <-chan error)
+}
+
+// ingestPath loads the documentation under path and stores it. With goDoc
+// set, the Go packages under path are ingested instead of documentation
+// files.
+func ingestPath(path string, goDoc bool, config Config, p *processor.Processor, vectorStore *store.VectorStore) {
+	if path == "" {
+		color.Red("Usage: /ingest <path> or /ingest-go <path>\n")
+		return
+	}
+
+	var (
+		readCount int32
+		src       documentSource
+		err       error
+	)
+	onProgress := func(string) {
+		atomic.AddInt32(&readCount, 1)
+	}
+	if goDoc {
+		src, err = source.NewGoPackagesWithConfig(source.GoPackagesConfig{
+			Root:       path,
+			OnProgress: onProgress,
+		})
+	} else {
+		src, err = source.NewFilesystemWithConfig(source.FilesystemConfig{
+			Root:       path,
+			Include:    config.FSInclude,
+			Exclude:    config.FSExclude,
+			OnProgress: onProgress,
+		})
+	}
+	if err != nil {
+		color.Red("Failed to open %s: %v\n", path, err)
+		return
+	}
+
+	color.Blue("\nIngesting from: %s", path)
+	spinner := getSpinner(" Reading files...")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var storedCount int32
+	docs, errc := src.Stream(ctx)
+	docCount := storeDocuments(docs, p, vectorStore, config.BatchSize, &storedCount, nil)
+	err = <-errc
+	spinner.Finish()
+
+	if errors.Is(err, context.Canceled) {
+		color.Yellow("\nIngestion interrupted, keeping %d documents\n", docCount)
+	} else if err != nil {
+		color.Red("Failed to ingest %s: %v\n", path, err)
+		return
+	}
+	if goDoc {
+		color.Green("✓ Ingested %d symbols from %d packages\n", docCount, atomic.LoadInt32(&readCount))
+	} else {
+		color.Green("✓ Ingested %d of %d files\n", docCount, atomic.LoadInt32(&readCount))
+	}
+	color.Green("✓ Stored %d chunks\n", atomic.LoadInt32(&storedCount))
+}
+
+// storeDocuments processes and stores documents as they arrive on docs,
+// writing them to the vector store in batches. It returns the number of
+// documents received and adds the number of stored chunks to storedCount.
+// onStored, when not nil, is called with the URL of every stored document.
+func storeDocuments(docs <-chan models.Document, p *processor.Processor, vectorStore *store.VectorStore, batchSize int, storedCount *int32, onStored func(url string)) int {
+	var docCount int
+	batch := make([]models.ProcessedDocument, 0, batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := vectorStore.Store(batch); err != nil {
+			color.Red("Failed to store batch: %v\n", err)
+		} else {
+			for _, doc := range batch {
+				atomic.AddInt32(storedCount, int32(len(doc.Chunks)))
+				if onStored != nil {
+					onStored(doc.URL)
+				}
+			}
+		}
+		batch = batch[:0]
+	}
+
+	for doc := range docs {
+		docCount++
+		processedDocs, err := p.Process([]models.Document{doc})
+		if err != nil {
+			color.Red("Failed to process document %s: %v\n", doc.URL, err)
+			continue
+		}
+
+		// The documents of a URL arrive together and are stored in the
+		// same batch, which replaces all of the URL's chunks
+		if len(batch) > 0 && len(batch) >= batchSize && batch[len(batch)-1].URL != doc.URL {
+			flush()
+		}
+		batch = append(batch, processedDocs...)
+	}
+	flush()
+
+	return docCount
+}
+
+// crawlSettings returns the scraper settings of a crawl from url. A replay
+// of an archived crawl uses the same settings to produce the same documents.
+func crawlSettings(url string, config Config, defaultExtractor scraper.ContentExtractor, extractorRules []scraper.ExtractorRule) scraper.ScraperConfig {
+	return scraper.ScraperConfig{
+		BaseURL:          url,
+		MaxDepth:         config.MaxDepth,
+		RateLimit:        config.RateLimit,
+		Workers:          config.Workers,
+		MaxQueueSize:     config.MaxQueueSize,
+		UserAgent:        config.UserAgent,
+		IgnoreRobots:     config.IgnoreRobots,
+		UseSitemaps:      config.UseSitemaps,
+		TrackingParams:   config.TrackingParams,
+		Retry:            config.Retry,
+		Scope:            config.Scope,
+		HostRateLimits:   config.HostRateLimits,
+		HTTPProfiles:     config.HTTPProfiles,
+		Extractors:       extractorRules,
+		DefaultExtractor: defaultExtractor,
+	}
+}
+
+// createArchive creates a WARC file in dir for a crawl from url, named after
+// the host and the time.
+func createArchive(dir, url string) (*warc.Writer, string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, "", fmt.Errorf("failed to create archive directory: %v", err)
+	}
+
+	host := "crawl"
+	if u, err := neturl.Parse(url); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	name := fmt.Sprintf("%s-%s.warc.gz", host, time.Now().Format("20060102-150405"))
+	path := filepath.Join(dir, name)
+
+	archive, err := warc.Create(path, scraper.DefaultUserAgent)
+	if err != nil {
+		return nil, "", err
+	}
+	return archive, path, nil
+}
+
+// replayArchive replays the crawls recorded in the WARC file at path with
+// the current scraper settings, but the recorded max depth, and stores the
+// documents, without fetching anything from the network.
+func replayArchive(path string, config Config, p *processor.Processor, vectorStore *store.VectorStore, defaultExtractor scraper.ContentExtractor, extractorRules []scraper.ExtractorRule) {
+	if path == "" {
+		color.Red("Usage: /replay <file.warc.gz>\n")
+		return
+	}
+
+	var pageCount int32
+	settings := crawlSettings("", config, defaultExtractor, extractorRules)
+	// Nothing is fetched from the sites, so nothing is rate limited
+	settings.RateLimit = 0
+	settings.HostRateLimits = nil
+	src, err := source.NewWARCWithConfig(source.WARCConfig{
+		Path:    path,
+		Scraper: settings,
+		OnProgress: func(string) {
+			atomic.AddInt32(&pageCount, 1)
+		},
+	})
+	if err != nil {
+		color.Red("Failed to open %s: %v\n", path, err)
+		return
+	}
+
+	color.Blue("\nReplaying: %s", path)
+	spinner := getSpinner(" Replaying crawl...")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var storedCount int32
+	docs, errc := src.Stream(ctx)
+	docCount := storeDocuments(docs, p, vectorStore, config.BatchSize, &storedCount, nil)
+	err = <-errc
+	spinner.Finish()
+
+	if errors.Is(err, context.Canceled) {
+		color.Yellow("\nReplay interrupted, keeping %d documents\n", docCount)
+	} else if err != nil {
+		color.Red("Failed to replay %s: %v\n", path, err)
+		return
+	}
+	color.Green("✓ Replayed %d documents from %d archived pages\n", docCount, atomic.LoadInt32(&pageCount))
+	color.Green("✓ Stored %d chunks\n", atomic.LoadInt32(&storedCount))
+}
+
+// scrapeURL crawls url and stores its documents, continuing from the
+// crawl's checkpoint when resume is set. It returns the crawl report and
+// false if the crawl failed.
+func scrapeURL(url string, resume bool, config Config, p *processor.Processor, vectorStore *store.VectorStore, checkpoints scraper.CheckpointStore, defaultExtractor scraper.ContentExtractor, extractorRules []scraper.ExtractorRule) (scraper.CrawlReport, bool) {
+	// Initialize scraper for this URL
+	var scrapeCount, disallowedCount, unchangedCount int32
+	scraperConfig := crawlSettings(url, config, defaultExtractor, extractorRules)
+	scraperConfig.PageCache = vectorStore
+	scraperConfig.Checkpoint = checkpoints
+	scraperConfig.CheckpointInterval = config.SaveInterval
+	scraperConfig.Resume = resume
+	scraperConfig.OnProgress = func(url string) {
+		atomic.AddInt32(&scrapeCount, 1)
+	}
+	scraperConfig.OnSkip = func(url string, reason string) {
+		switch reason {
+		case scraper.SkipDisallowed:
+			atomic.AddInt32(&disallowedCount, 1)
+		case scraper.SkipNotModified:
+			// Unchanged pages are only counted, as a re-crawl may have many
+			atomic.AddInt32(&unchangedCount, 1)
+			return
+		}
+		color.Yellow("\nSkipped %s: %s", url, reason)
+	}
+
+	if config.ArchiveDir != "" {
+		archive, path, err := createArchive(config.ArchiveDir, url)
+		if err != nil {
+			color.Red("%v\n", err)
+			return scraper.CrawlReport{}, false
+		}
+		defer func() {
+			if err := archive.Close(); err != nil {
+				color.Red("Failed to write archive: %v\n", err)
+				return
+			}
+			color.Green("✓ Archived the crawl to %s\n", path)
+		}()
+		scraperConfig.Archive = archive
+	}
+
+	s, err := scraper.NewWithConfig(scraperConfig)
+	if err != nil {
+		color.Red("Failed to initialize scraper: %v\n", err)
+		return scraper.CrawlReport{}, false
+	}
+
+	// Create progress bar for scraping
+	scrapingBar := getProgressBar(-1, " Scraping documentation...")
+	startTime := time.Now()
+	lastCount := int32(0)
+	var storedCount int32
+	progressDone := make(chan struct{})
+
+	// Start progress updater
+	go func() {
+		for {
+			select {
+			case <-progressDone:
+				return
+			case <-time.After(100 * time.Millisecond):
+			}
+
+			count := atomic.LoadInt32(&scrapeCount)
+			scrapingBar.Set(int(count))
+
+			if count > lastCount {
+				elapsed := time.Since(startTime).Seconds()
+				rate := float64(count) / elapsed
+				scrapingBar.Describe(color.BlueString(
+					"Scraping documentation (%.1f pages/sec, %d chunks stored)",
+					rate, atomic.LoadInt32(&storedCount)))
+			}
+			lastCount = count
+		}
+	}()
+
+	// Scrape the URL, stopping early on Ctrl+C. Documents are
+	// processed and stored while the crawl is still running.
+	scrapeCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	docs, errc := s.ScrapeStream(scrapeCtx, url)
+
+	docCount := storeDocuments(docs, p, vectorStore, config.BatchSize, &storedCount, func(url string) {
+		s.MarkStored(url)
+	})
+
+	err = <-errc
+	stop()
+	close(progressDone)
+	scrapingBar.Finish()
+
+	// Record the documents stored after the crawl itself returned
+	if err := s.SaveCheckpoint(context.Background()); err != nil {
+		color.Red("%v\n", err)
+	}
+	report := s.Report()
+
+	if errors.Is(err, context.Canceled) {
+		color.Yellow("\nScraping interrupted, keeping %d documents\n", docCount)
+		if checkpoints != nil {
+			color.Yellow("Continue with: /resume %s\n", url)
+		}
+	} else if err != nil {
+		color.Red("Failed to scrape URL: %v\n", err)
+		printReportSummary(report)
+		return report, false
+	}
+	color.Green("✓ Scraped %d documents\n", docCount)
+	if unchanged := atomic.LoadInt32(&unchangedCount); unchanged > 0 {
+		color.Green("✓ %d pages unchanged since the last crawl\n", unchanged)
+	}
+	if disallowed := atomic.LoadInt32(&disallowedCount); disallowed > 0 {
+		color.Yellow("Skipped %d URLs disallowed by robots.txt\n", disallowed)
+	}
+	color.Green("✓ Stored %d chunks\n", atomic.LoadInt32(&storedCount))
+	color.Green("✓ URL processed and stored\n")
+	printReportSummary(report)
+	return report, true
+}
+
+// maxBrokenLinks is how many broken links are listed after a crawl.
+const maxBrokenLinks = 10
+
+// printReportSummary prints the page counts of a crawl and its broken links.
+func printReportSummary(report scraper.CrawlReport) {
+	fetched := report.Count(models.PageFetched) + report.Count(models.PageStored)
+	color.Cyan("Visited %d URLs: %d fetched, %d skipped, %d failed\n",
+		len(report.Pages)-report.Count(models.PageQueued), fetched,
+		report.Count(models.PageSkipped), report.Count(models.PageFailed))
+
+	broken := report.BrokenLinks()
+	if len(broken) > 0 {
+		color.Yellow("Found %d broken links:\n", len(broken))
+	}
+	for i, page := range broken {
+		if i == maxBrokenLinks {
+			color.Yellow("  ... and %d more\n", len(broken)-maxBrokenLinks)
+			break
+		}
+		line := fmt.Sprintf("  %s %s", page.Reason, page.URL)
+		if page.StatusCode != 0 {
+			line = fmt.Sprintf("  %d %s", page.StatusCode, page.URL)
+		}
+		if page.Referrer != "" {
+			line += " (linked from " + page.Referrer + ")"
+		}
+		color.Yellow("%s\n", line)
+	}
+	color.Cyan("Type /report for the full crawl report, or /report <file.json|file.csv> to export it\n")
+}
+
+// showReport prints report, or writes it to path as JSON or CSV depending on
+// the file extension.
+func showReport(report *scraper.CrawlReport, path string) {
+	if report == nil {
+		color.Red("No crawl to report on yet\n")
+		return
+	}
+	if path == "" {
+		if err := report.WriteText(os.Stdout); err != nil {
+			color.Red("Failed to print report: %v\n", err)
+		}
+		return
+	}
+
+	var write func(io.Writer) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		write = report.WriteJSON
+	case ".csv":
+		write = report.WriteCSV
+	default:
+		color.Red("Unknown report format %s, use .json or .csv\n", filepath.Ext(path))
+		return
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		color.Red("Failed to create %s: %v\n", path, err)
+		return
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		color.Red("Failed to write report: %v\n", err)
+		return
+	}
+	if err := file.Close(); err != nil {
+		color.Red("Failed to write report: %v\n", err)
+		return
+	}
+	color.Green("✓ Wrote report of %d URLs to %s\n", len(report.Pages), path)
+}
+
 func getProgressBar(total int, description string) *progressbar.ProgressBar {
 	return progressbar.NewOptions(total,
 		progressbar.OptionSetDescription(color.BlueString(description)),
@@ -137,10 +618,14 @@ func run(config Config) error {
 		return fmt.Errorf("failed to initialize chat engine: %v", err)
 	}
 
-	processor := processor.NewWithConfig(processor.ProcessorConfig{
-		ChunkSize:    config.ChunkSize,
-		ChunkOverlap: 200,
-	})
+	// Each source may chunk its documents with a different strategy
+	processors := make(map[string]*processor.Processor)
+	for _, source := range []string{"web", "warc", "filesystem", "godoc"} {
+		processors[source], err = app.NewProcessor(config.Processor, source)
+		if err != nil {
+			return err
+		}
+	}
 
 	vectorStore, err := store.NewWithConfig(store.VectorStoreConfig{
 		ConnString: config.DBUrl,
@@ -155,8 +640,21 @@ func run(config Config) error {
 
 	defer vectorStore.Close()
 
+	defaultExtractor, extractorRules, err := app.BuildExtractors(config.Extractor, config.Extractors)
+	if err != nil {
+		return fmt.Errorf("failed to initialize content extractors: %v", err)
+	}
+
+	checkpoints, err := buildCheckpointStore(config, vectorStore)
+	if err != nil {
+		return fmt.Errorf("failed to initialize checkpoints: %v", err)
+	}
+
 	// Interactive chat loop with colored output
-	color.Cyan("\nChat with Loreum Sensors and Agents (type 'exit' to quit)")
+	color.Cyan("\nChat with Loreum Sensors and Agents (type 'exit' to quit, '/ingest <path>' or '/ingest-go <path>' to add local docs, '/resume <url>' to continue a crawl, '/replay <file>' to ingest an archived crawl, '/report' for the last crawl report)")
+
+	// Report of the latest crawl, for /report
+	var lastReport *scraper.CrawlReport
 
 	scanner := bufio.NewScanner(os.Stdin)
 	userPrompt := color.New(color.FgGreen).PrintfFunc()
@@ -173,6 +671,39 @@ func run(config Config) error {
 			break
 		}
 
+		if path, ok := strings.CutPrefix(strings.TrimSpace(query), "/ingest-go"); ok {
+			ingestPath(strings.TrimSpace(path), true, config, processors["godoc"], vectorStore)
+			continue
+		}
+		if path, ok := strings.CutPrefix(strings.TrimSpace(query), "/ingest"); ok {
+			ingestPath(strings.TrimSpace(path), false, config, processors["filesystem"], vectorStore)
+			continue
+		}
+
+		if url, ok := strings.CutPrefix(strings.TrimSpace(query), "/resume"); ok {
+			url = strings.TrimSpace(url)
+			if url == "" || checkpoints == nil {
+				color.Red("Usage: /resume <url>, with scraper checkpoints enabled\n")
+				continue
+			}
+			color.Blue("\nResuming: %s", url)
+			report, _ := scrapeURL(url, true, config, processors["web"], vectorStore, checkpoints, defaultExtractor, extractorRules)
+			if report.StartURL != "" {
+				lastReport = &report
+			}
+			continue
+		}
+
+		if path, ok := strings.CutPrefix(strings.TrimSpace(query), "/replay"); ok {
+			replayArchive(strings.TrimSpace(path), config, processors["warc"], vectorStore, defaultExtractor, extractorRules)
+			continue
+		}
+
+		if path, ok := strings.CutPrefix(strings.TrimSpace(query), "/report"); ok {
+			showReport(lastReport, strings.TrimSpace(path))
+			continue
+		}
+
 		// Check if input contains a URL
 		urlRegex := regexp.MustCompile(`https?://[^\s]+`)
 		if url := urlRegex.FindString(query); url != "" {
@@ -181,117 +712,13 @@ func run(config Config) error {
 				url = "https://" + url
 			}
 
-			// Initialize scraper for this URL
-			var scrapeCount int32
-			s, err := scraper.NewWithConfig(scraper.ScraperConfig{
-				BaseURL:   url,
-				MaxDepth:  config.MaxDepth,
-				RateLimit: config.RateLimit,
-				OnProgress: func(url string) {
-					atomic.AddInt32(&scrapeCount, 1)
-				},
-			})
-			if err != nil {
-				color.Red("Failed to initialize scraper: %v\n", err)
-				continue
+			report, ok := scrapeURL(url, false, config, processors["web"], vectorStore, checkpoints, defaultExtractor, extractorRules)
+			if report.StartURL != "" {
+				lastReport = &report
 			}
-
-			// Create progress bar for scraping
-			scrapingBar := getProgressBar(-1, " Scraping documentation...")
-			startTime := time.Now()
-			lastCount := int32(0)
-
-			// Start progress updater
-			go func() {
-				for {
-					count := atomic.LoadInt32(&scrapeCount)
-					scrapingBar.Set(int(count))
-
-					if count > lastCount {
-						elapsed := time.Since(startTime).Seconds()
-						rate := float64(count) / elapsed
-						scrapingBar.Describe(color.BlueString(
-							"Scraping documentation (%.1f pages/sec)", rate))
-					}
-					lastCount = count
-					time.Sleep(100 * time.Millisecond)
-				}
-			}()
-
-			// Scrape the URL
-			docs, err := s.Scrape(url)
-			if err != nil {
-				scrapingBar.Finish()
-				color.Red("Failed to scrape URL: %v\n", err)
+			if !ok {
 				continue
 			}
-			scrapingBar.Finish()
-			color.Green("✓ Scraped %d documents\n", len(docs))
-
-			// Process documents with progress bar
-			processed := make([]models.ProcessedDocument, 0)
-			var processedCount int32
-			processingBar := getProgressBar(-1, " Processing documents")
-
-			// Initialize variables for progress tracking
-			startTime1 := time.Now()
-			lastCount1 := int32(0)
-
-			// Start the progress updater goroutine
-			go func() {
-				for {
-					count := atomic.LoadInt32(&processedCount)
-					processingBar.Set(int(count))
-					if count > lastCount1 {
-						elapsed := time.Since(startTime1).Seconds()
-						rate := float64(count) / elapsed
-						processingBar.Describe(color.BlueString(
-							"Processing documents (%.1f docs/sec)", rate))
-					}
-					lastCount1 = count
-					time.Sleep(100 * time.Millisecond)
-				}
-			}()
-
-			for _, doc := range docs {
-				processedDocs, err := processor.Process([]models.Document{doc})
-				if err != nil {
-					color.Red("Failed to process document %s: %v\n", doc.URL, err)
-					continue
-				}
-
-				// Update the count when processing is successful
-				atomic.AddInt32(&processedCount, 1)
-				processed = append(processed, processedDocs...)
-			}
-			processingBar.Finish()
-			color.Green("✓ Processed into %d chunks\n", len(processed))
-
-			// Store in vector database
-			storageBar := getProgressBar(-1, " Storing in vector database")
-			startTime = time.Now()
-			batchSize := config.BatchSize
-
-			for i := 0; i < len(processed); i += batchSize {
-				end := i + batchSize
-				if end > len(processed) {
-					end = len(processed)
-				}
-				batch := processed[i:end]
-
-				if err := vectorStore.Store(batch); err != nil {
-					color.Red("Failed to store batch: %v\n", err)
-					continue
-				}
-				storageBar.Add(len(batch))
-
-				elapsed := time.Since(startTime).Seconds()
-				rate := float64(i+len(batch)) / elapsed
-				storageBar.Describe(color.BlueString(
-					"Storing in vector database (%.1f chunks/sec)", rate))
-			}
-			storageBar.Finish()
-			color.Green("✓ URL processed and stored\n")
 
 			if strings.TrimSpace(query) == url {
 				continue
@@ -300,8 +727,9 @@ func run(config Config) error {
 
 		// Regular chat flow continues here...
 		emb := llm.NewEmbedder()
+		// Normalize the question like the chunks it is compared with
 		queryArray := make([]string, 1)
-		queryArray[0] = query
+		queryArray[0] = processors["web"].Normalize(query)
 
 		embeddings, err := emb.Embed.CreateEmbedding(context.Background(), queryArray)
 		if err != nil {
@@ -0,0 +1,95 @@
+// Package app builds the components of the command line tool and the
+// server from their configuration, so both entry points set them up the
+// same way.
+package app
+
+import (
+	"fmt"
+
+	"github.com/xhad/yes/pkg/config"
+	"github.com/xhad/yes/pkg/llm"
+	"github.com/xhad/yes/pkg/processor"
+	"github.com/xhad/yes/pkg/scraper"
+)
+
+// NewProcessor returns the processor of documents from source, which chunks
+// them with the strategy configured for the source.
+func NewProcessor(cfg config.ProcessorConfig, source string) (*processor.Processor, error) {
+	strategy := cfg.Chunker
+	if s, ok := cfg.Chunkers[source]; ok {
+		strategy = s
+	}
+
+	processorConfig := processor.ProcessorConfig{
+		ChunkSize:            cfg.ChunkSize,
+		ChunkOverlap:         cfg.ChunkOverlap,
+		EmbeddingLimit:       llm.EmbeddingInputLimit(llm.DefaultEmbeddingModel),
+		Strategy:             strategy,
+		WindowSize:           cfg.WindowSize,
+		BreakpointPercentile: cfg.BreakpointPercentile,
+		Lowercase:            cfg.Lowercase,
+		FoldAccents:          cfg.FoldAccents,
+		StripPunctuation:     cfg.StripPunctuation,
+		RemoveStopwords:      cfg.RemoveStopwords,
+		CustomStopwords:      cfg.Stopwords,
+	}
+	if strategy == processor.StrategySemantic {
+		processorConfig.Embedder = llm.NewEmbedder().Embed
+	}
+
+	p, err := processor.New(processorConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize %s processor: %v", source, err)
+	}
+	return &p, nil
+}
+
+// BuildExtractors turns the configured extractor settings into the default
+// and per-host content extractors used by the scraper.
+func BuildExtractors(kind string, rules []config.ExtractorConfig) (scraper.ContentExtractor, []scraper.ExtractorRule, error) {
+	defaultExtractor, err := scraper.NewExtractor(kind, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var extractorRules []scraper.ExtractorRule
+	for _, rule := range rules {
+		extractor, err := scraper.NewExtractor(rule.Type, rule.Include, rule.Exclude)
+		if err != nil {
+			return nil, nil, err
+		}
+		extractorRules = append(extractorRules, scraper.ExtractorRule{
+			Hosts:     rule.Hosts,
+			Extractor: extractor,
+		})
+	}
+
+	return defaultExtractor, extractorRules, nil
+}
+
+// BuildHTTPProfiles turns the configured HTTP profiles into scraper
+// profiles. Secrets are resolved by the scraper.
+func BuildHTTPProfiles(profiles []config.HTTPProfile) []scraper.HTTPProfile {
+	secret := func(s config.Secret) scraper.Secret {
+		return scraper.Secret{Value: s.Value, Env: s.Env, File: s.File}
+	}
+
+	var httpProfiles []scraper.HTTPProfile
+	for _, profile := range profiles {
+		headers := make(map[string]scraper.Secret, len(profile.Headers))
+		for name, value := range profile.Headers {
+			headers[name] = secret(value)
+		}
+		httpProfiles = append(httpProfiles, scraper.HTTPProfile{
+			Hosts:       profile.Hosts,
+			Headers:     headers,
+			BearerToken: secret(profile.BearerToken),
+			Username:    profile.BasicAuth.Username,
+			Password:    secret(profile.BasicAuth.Password),
+			CookieFile:  profile.CookieFile,
+			UserAgent:   profile.UserAgent,
+			Proxy:       secret(profile.Proxy),
+		})
+	}
+	return httpProfiles
+}
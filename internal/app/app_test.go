@@ -0,0 +1,61 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/pkg/config"
+	"github.com/xhad/yes/pkg/scraper"
+)
+
+func TestNewProcessor(t *testing.T) {
+	cfg := config.ProcessorConfig{
+		ChunkSize:    256,
+		ChunkOverlap: 32,
+		Chunker:      "fixed",
+		Chunkers:     map[string]string{"godoc": "bogus"},
+	}
+
+	p, err := NewProcessor(cfg, "web")
+	require.NoError(t, err)
+	assert.NotNil(t, p)
+
+	// The strategy of a source replaces the default one
+	_, err = NewProcessor(cfg, "godoc")
+	assert.EqualError(t, err, "failed to initialize godoc processor: unknown chunking strategy: bogus")
+}
+
+func TestBuildExtractors(t *testing.T) {
+	defaultExtractor, rules, err := BuildExtractors("readability", []config.ExtractorConfig{
+		{Hosts: []string{"docs.example.com"}, Include: []string{"article"}},
+	})
+	require.NoError(t, err)
+	assert.IsType(t, &scraper.ReadabilityExtractor{}, defaultExtractor)
+	require.Len(t, rules, 1)
+	assert.Equal(t, []string{"docs.example.com"}, rules[0].Hosts)
+	assert.Equal(t, &scraper.SelectorExtractor{Include: []string{"article"}}, rules[0].Extractor)
+
+	_, _, err = BuildExtractors("", []config.ExtractorConfig{{Type: "bogus"}})
+	assert.Error(t, err)
+}
+
+func TestBuildHTTPProfiles(t *testing.T) {
+	profile := config.HTTPProfile{
+		Hosts:       []string{"api.example.com"},
+		Headers:     map[string]config.Secret{"X-Key": {Env: "API_KEY"}},
+		BearerToken: config.Secret{File: "/run/secrets/token"},
+		UserAgent:   "docs-bot",
+	}
+	profile.BasicAuth.Username = "ada"
+	profile.BasicAuth.Password = config.Secret{Value: "secret"}
+
+	assert.Equal(t, []scraper.HTTPProfile{{
+		Hosts:       []string{"api.example.com"},
+		Headers:     map[string]scraper.Secret{"X-Key": {Env: "API_KEY"}},
+		BearerToken: scraper.Secret{File: "/run/secrets/token"},
+		Username:    "ada",
+		Password:    scraper.Secret{Value: "secret"},
+		UserAgent:   "docs-bot",
+	}}, BuildHTTPProfiles([]config.HTTPProfile{profile}))
+}
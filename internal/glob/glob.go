@@ -0,0 +1,122 @@
+// Package glob matches slash-separated paths against shell-style patterns.
+//
+// Patterns support "*" (any run of characters except "/"), "?" (a single
+// character except "/"), character classes such as "[a-z]" and "**", which
+// matches across directories: "docs/**/*.md" matches "docs/a.md" and
+// "docs/a/b/c.md". A pattern without a "/" is matched against the last path
+// element only, so "*.md" matches Markdown files in every directory.
+package glob
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Pattern is a compiled glob.
+type Pattern struct {
+	raw      string
+	baseOnly bool
+	re       *regexp.Regexp
+}
+
+// Compile parses a glob pattern.
+func Compile(pattern string) (*Pattern, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				// "**/" also matches no directory at all.
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					expr.WriteString("(?:.*/)?")
+				} else {
+					expr.WriteString(".*")
+				}
+			} else {
+				expr.WriteString("[^/]*")
+			}
+		case '?':
+			expr.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid glob %q: unterminated character class", pattern)
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+			}
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob %q: %v", pattern, err)
+	}
+
+	return &Pattern{
+		raw:      pattern,
+		baseOnly: !strings.Contains(pattern, "/"),
+		re:       re,
+	}, nil
+}
+
+// MustCompile is like Compile but panics on an invalid pattern.
+func MustCompile(pattern string) *Pattern {
+	p, err := Compile(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Match reports whether the slash-separated name matches the pattern.
+func (p *Pattern) Match(name string) bool {
+	if p.baseOnly {
+		name = path.Base(name)
+	}
+	return p.re.MatchString(name)
+}
+
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// CompileAll compiles every pattern in patterns.
+func CompileAll(patterns []string) ([]*Pattern, error) {
+	compiled := make([]*Pattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		p, err := Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, p)
+	}
+	return compiled, nil
+}
+
+// MatchAny reports whether name matches at least one of patterns.
+func MatchAny(patterns []*Pattern, name string) bool {
+	for _, p := range patterns {
+		if p.Match(name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package glob
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		name     string
+		expected bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/guide/intro.md", true},
+		{"*.md", "docs/guide/intro.rst", false},
+		{"docs/*.md", "docs/intro.md", true},
+		{"docs/*.md", "docs/guide/intro.md", false},
+		{"docs/**/*.md", "docs/intro.md", true},
+		{"docs/**/*.md", "docs/a/b/intro.md", true},
+		{"**/node_modules", "web/node_modules", true},
+		{"**/node_modules", "node_modules", true},
+		{"**/node_modules/**", "node_modules/pkg/readme.md", true},
+		{"/v1/**", "/v1/users", true},
+		{"/v1/**", "/v2/users", false},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"[!.]*", ".hidden", false},
+		{"[a-c]*.go", "b_test.go", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, MustCompile(tt.pattern).Match(tt.name))
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	_, err := Compile("[abc")
+	assert.Error(t, err)
+
+	_, err = CompileAll([]string{"*.md", "[z-a]"})
+	assert.Error(t, err)
+}
+
+func TestMatchAny(t *testing.T) {
+	patterns, err := CompileAll([]string{"*.md", "*.rst"})
+	assert.NoError(t, err)
+	assert.True(t, MatchAny(patterns, "a/b.rst"))
+	assert.False(t, MatchAny(patterns, "a/b.go"))
+	assert.False(t, MatchAny(nil, "a/b.md"))
+}
@@ -1,15 +1,86 @@
 package models
 
+import "time"
+
 type Document struct {
 	ID       string
 	URL      string
 	Title    string
 	Content  string
+	Markdown string // main content with headings, lists, tables and code kept
 	Metadata map[string]interface{}
+	Links    []string // outgoing links of a web page, stored once per page for re-crawls
 }
 
 type ProcessedDocument struct {
 	Document
-	Chunks    []string
-	Embedding [][]float32
+	Chunks       []string // original text of each chunk, for storage and prompts
+	HeadingPaths []string // heading path of each chunk, such as "Guide > Auth > Tokens"
+	Normalized   []string // normalized text of each chunk, for embedding and keyword matching
+	Embedding    [][]float32
+
+	// Code describes the code blocks of each chunk, and is zero for chunks
+	// of prose.
+	Code []ChunkCode
+}
+
+// ChunkCode describes the code blocks a chunk holds. Code blocks are never
+// split across chunks.
+type ChunkCode struct {
+	Blocks    int      // number of code blocks in the chunk
+	Languages []string // languages of the blocks, where named
+	Context   string   // prose around the block of a chunk holding only code
+}
+
+// EmbeddingText is the text embedded for a chunk: the chunk prefixed with
+// its heading path, so the vector reflects where in a document it is.
+func EmbeddingText(headingPath, chunk string) string {
+	if headingPath == "" {
+		return chunk
+	}
+	return headingPath + "\n\n" + chunk
+}
+
+// CachedPage is what a previous crawl recorded about a page. It lets the
+// scraper revalidate the page with a conditional request.
+type CachedPage struct {
+	ETag         string
+	LastModified string
+	Links        []string
+}
+
+// Statuses of a page in a CrawlCheckpoint.
+const (
+	PageQueued  = "queued"  // waiting to be fetched
+	PageFetched = "fetched" // emitted, but not yet confirmed stored
+	PageStored  = "stored"
+	PageSkipped = "skipped"
+	PageFailed  = "failed"
+)
+
+// CrawlCheckpoint is the saved state of a crawl: every URL it has seen with
+// its status. Queued pages form the frontier; the others are visited.
+type CrawlCheckpoint struct {
+	StartURL  string      `json:"startURL"`
+	Complete  bool        `json:"complete"` // the crawl ran to the end
+	UpdatedAt time.Time   `json:"updatedAt"`
+	Pages     []CrawlPage `json:"pages"`
+}
+
+// CrawlPage is a URL recorded in a CrawlCheckpoint, along with what
+// fetching it returned.
+type CrawlPage struct {
+	URL         string   `json:"url"`
+	FinalURL    string   `json:"finalURL,omitempty"` // document URL, when a redirect or canonical link changed it
+	Referrer    string   `json:"referrer,omitempty"` // page the URL was first found on
+	Depth       int      `json:"depth"`
+	Priority    int64    `json:"priority,omitempty"`
+	Root        bool     `json:"root,omitempty"`
+	Status      string   `json:"status"`
+	Reason      string   `json:"reason,omitempty"` // why the page failed or was skipped
+	StatusCode  int      `json:"statusCode,omitempty"`
+	Redirects   []string `json:"redirects,omitempty"` // every URL redirected to, in order
+	ContentType string   `json:"contentType,omitempty"`
+	Bytes       int64    `json:"bytes,omitempty"`
+	LatencyMS   int64    `json:"latencyMs,omitempty"`
 }
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -23,18 +24,14 @@ type Config struct {
 		BatchSize int    `yaml:"batch_size"`
 	} `yaml:"database"`
 
-	Scraper struct {
-		MaxDepth          int      `yaml:"max_depth"`
-		RateLimit         float64  `yaml:"rate_limit"`
-		IgnorePatterns    []string `yaml:"ignore_patterns"`
-		AllowedExtensions []string `yaml:"allowed_extensions"`
-	} `yaml:"scraper"`
+	Scraper ScraperConfig `yaml:"scraper"`
 
-	Processor struct {
-		ChunkSize       int  `yaml:"chunk_size"`
-		ChunkOverlap    int  `yaml:"chunk_overlap"`
-		RemoveStopwords bool `yaml:"remove_stopwords"`
-	} `yaml:"processor"`
+	Filesystem struct {
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+	} `yaml:"filesystem"`
+
+	Processor ProcessorConfig `yaml:"processor"`
 
 	UI struct {
 		Streaming bool   `yaml:"streaming"`
@@ -42,6 +39,119 @@ type Config struct {
 	} `yaml:"ui"`
 }
 
+type ScraperConfig struct {
+	MaxDepth          int               `yaml:"max_depth"`
+	RateLimit         float64           `yaml:"rate_limit"`
+	IgnorePatterns    []string          `yaml:"ignore_patterns"`
+	AllowedExtensions []string          `yaml:"allowed_extensions"`
+	Workers           int               `yaml:"workers"`
+	MaxQueueSize      int               `yaml:"max_queue_size"`
+	UserAgent         string            `yaml:"user_agent"`
+	IgnoreRobots      bool              `yaml:"ignore_robots"`
+	UseSitemaps       bool              `yaml:"use_sitemaps"`
+	TrackingParams    []string          `yaml:"tracking_params"`
+	Retry             RetryConfig       `yaml:"retry"`
+	Scope             ScopeConfig       `yaml:"scope"`
+	HostRateLimits    []HostRateLimit   `yaml:"host_rate_limits"`
+	HTTPProfiles      []HTTPProfile     `yaml:"http_profiles"`
+	Checkpoint        CheckpointConfig  `yaml:"checkpoint"`
+	ArchiveDir        string            `yaml:"archive_dir"` // WARC files of every crawl, disabled if empty
+	Extractor         string            `yaml:"extractor"`
+	Extractors        []ExtractorConfig `yaml:"extractors"`
+}
+
+// ProcessorConfig controls how documents are split into chunks. Chunker is
+// the default chunking strategy and Chunkers overrides it for a source: web,
+// warc, filesystem or godoc. The normalization steps apply to the text that
+// is embedded, while chunks are stored as they are.
+type ProcessorConfig struct {
+	ChunkSize            int               `yaml:"chunk_size"`    // tokens
+	ChunkOverlap         int               `yaml:"chunk_overlap"` // tokens
+	Lowercase            bool              `yaml:"lowercase"`
+	FoldAccents          bool              `yaml:"fold_accents"`
+	StripPunctuation     bool              `yaml:"strip_punctuation"`
+	RemoveStopwords      bool              `yaml:"remove_stopwords"`
+	Stopwords            []string          `yaml:"stopwords"` // in addition to the built-in English ones
+	Chunker              string            `yaml:"chunker"`
+	Chunkers             map[string]string `yaml:"chunkers"`
+	WindowSize           int               `yaml:"window_size"`           // sentences around a sentence-window chunk
+	BreakpointPercentile float64           `yaml:"breakpoint_percentile"` // semantic chunk boundaries
+}
+
+// RetryConfig controls retries of failed scraper requests.
+type RetryConfig struct {
+	MaxAttempts int           `yaml:"max_attempts"`
+	BaseDelay   time.Duration `yaml:"base_delay"`
+	MaxDelay    time.Duration `yaml:"max_delay"`
+}
+
+// ScopeConfig restricts a crawl to a set of hosts and paths. Include and
+// exclude rules are globs matched against the URL path, or regular
+// expressions when prefixed with "re:".
+type ScopeConfig struct {
+	Hosts        []string `yaml:"hosts"`
+	PathPrefixes []string `yaml:"path_prefixes"`
+	Include      []string `yaml:"include"`
+	Exclude      []string `yaml:"exclude"`
+}
+
+// HostRateLimit overrides the scraper rate limit for a set of hosts.
+type HostRateLimit struct {
+	Hosts     []string `yaml:"hosts"`
+	RateLimit float64  `yaml:"rate_limit"`
+}
+
+// HTTPProfile sets the headers, credentials and proxy used for a set of
+// hosts.
+type HTTPProfile struct {
+	Hosts       []string          `yaml:"hosts"`
+	Headers     map[string]Secret `yaml:"headers"`
+	BearerToken Secret            `yaml:"bearer_token"`
+	BasicAuth   struct {
+		Username string `yaml:"username"`
+		Password Secret `yaml:"password"`
+	} `yaml:"basic_auth"`
+	CookieFile string `yaml:"cookie_file"`
+	UserAgent  string `yaml:"user_agent"`
+	Proxy      Secret `yaml:"proxy"`
+}
+
+// Secret is a value read from an environment variable or a file. It may
+// also be given inline, either as {value: ...} or as a plain string, which
+// is meant for values that are not secret, such as most headers.
+type Secret struct {
+	Value string `yaml:"value"`
+	Env   string `yaml:"env"`
+	File  string `yaml:"file"`
+}
+
+// UnmarshalYAML accepts a plain string as an inline value.
+func (s *Secret) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Value = node.Value
+		return nil
+	}
+	type plain Secret
+	return node.Decode((*plain)(s))
+}
+
+// CheckpointConfig selects where the crawl state is saved so that an
+// interrupted crawl can be resumed. Backend is "file", "postgres" or empty
+// to disable checkpoints.
+type CheckpointConfig struct {
+	Backend  string        `yaml:"backend"`
+	Dir      string        `yaml:"dir"` // for the file backend
+	Interval time.Duration `yaml:"interval"`
+}
+
+// ExtractorConfig selects how main content is extracted on a set of hosts.
+type ExtractorConfig struct {
+	Hosts   []string `yaml:"hosts"`
+	Type    string   `yaml:"type"`
+	Include []string `yaml:"include"`
+	Exclude []string `yaml:"exclude"`
+}
+
 func LoadConfig(path string) (*Config, error) {
 	// If no path provided, try default locations
 	if path == "" {
@@ -121,14 +231,35 @@ func applyDefaults(config *Config) {
 		config.Scraper.RateLimit = 2.0
 	}
 	if len(config.Scraper.AllowedExtensions) == 0 {
-		config.Scraper.AllowedExtensions = []string{".html", ".htm", "/", ""}
+		config.Scraper.AllowedExtensions = []string{".html", ".htm", ".pdf", "/", ""}
+	}
+	if config.Scraper.Workers == 0 {
+		config.Scraper.Workers = 4
+	}
+	if config.Scraper.MaxQueueSize == 0 {
+		config.Scraper.MaxQueueSize = 10000
+	}
+	if config.Scraper.Retry.MaxAttempts == 0 {
+		config.Scraper.Retry.MaxAttempts = 3
+	}
+	if config.Scraper.Retry.BaseDelay == 0 {
+		config.Scraper.Retry.BaseDelay = 500 * time.Millisecond
+	}
+	if config.Scraper.Retry.MaxDelay == 0 {
+		config.Scraper.Retry.MaxDelay = 30 * time.Second
+	}
+	if config.Scraper.Checkpoint.Dir == "" {
+		config.Scraper.Checkpoint.Dir = ".yes/checkpoints"
+	}
+	if config.Scraper.Checkpoint.Interval == 0 {
+		config.Scraper.Checkpoint.Interval = 30 * time.Second
 	}
 
 	if config.Processor.ChunkSize == 0 {
-		config.Processor.ChunkSize = 1000
+		config.Processor.ChunkSize = 512
 	}
 	if config.Processor.ChunkOverlap == 0 {
-		config.Processor.ChunkOverlap = 200
+		config.Processor.ChunkOverlap = 64
 	}
 
 	if config.UI.Theme == "" {
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -30,6 +32,33 @@ database:
 scraper:
   max_depth: 5
   rate_limit: 1.5
+  workers: 8
+  user_agent: "test-bot/1.0"
+  ignore_robots: true
+  use_sitemaps: true
+  tracking_params: ["utm_*", "sessionid"]
+  scope:
+    hosts: ["docs.example.com", "api.example.com"]
+    exclude: ["/v1/**", "re:^/internal/"]
+  host_rate_limits:
+    - hosts: ["api.example.com"]
+      rate_limit: 0.5
+  checkpoint:
+    backend: postgres
+  archive_dir: archives
+  http_profiles:
+    - hosts: ["docs.internal.example.com"]
+      headers: {X-Team: docs, X-Api-Key: {env: DOCS_API_KEY}}
+      basic_auth: {username: ci, password: {file: /run/secrets/docs_password}}
+  retry:
+    max_attempts: 5
+    base_delay: 250ms
+  extractor: "readability"
+  extractors:
+    - hosts: ["docs.example.com", "*.example.org"]
+      type: "selectors"
+      include: ["main .docs-body"]
+      exclude: [".sidebar", "nav"]
   ignore_patterns:
     - "/test/"
   allowed_extensions:
@@ -40,6 +69,9 @@ processor:
   chunk_size: 500
   chunk_overlap: 100
   remove_stopwords: true
+  chunker: "recursive"
+  chunkers:
+    godoc: "fixed"
 
 ui:
   streaming: false
@@ -59,7 +91,35 @@ ui:
 	assert.Equal(t, 0.5, config.LLM.Temperature)
 	assert.Equal(t, "postgres://localhost:5432/test", config.Database.URL)
 	assert.Equal(t, 5, config.Scraper.MaxDepth)
+	assert.Equal(t, 8, config.Scraper.Workers)
+	assert.Equal(t, 10000, config.Scraper.MaxQueueSize)
+	assert.Equal(t, "test-bot/1.0", config.Scraper.UserAgent)
+	assert.True(t, config.Scraper.IgnoreRobots)
+	assert.True(t, config.Scraper.UseSitemaps)
+	assert.Equal(t, []string{"utm_*", "sessionid"}, config.Scraper.TrackingParams)
+	assert.Equal(t, []string{"docs.example.com", "api.example.com"}, config.Scraper.Scope.Hosts)
+	assert.Equal(t, []string{"/v1/**", "re:^/internal/"}, config.Scraper.Scope.Exclude)
+	require.Len(t, config.Scraper.HostRateLimits, 1)
+	assert.Equal(t, 0.5, config.Scraper.HostRateLimits[0].RateLimit)
+	assert.Equal(t, "postgres", config.Scraper.Checkpoint.Backend)
+	assert.Equal(t, "archives", config.Scraper.ArchiveDir)
+	require.Len(t, config.Scraper.HTTPProfiles, 1)
+	profile := config.Scraper.HTTPProfiles[0]
+	assert.Equal(t, Secret{Value: "docs"}, profile.Headers["X-Team"])
+	assert.Equal(t, Secret{Env: "DOCS_API_KEY"}, profile.Headers["X-Api-Key"])
+	assert.Equal(t, "ci", profile.BasicAuth.Username)
+	assert.Equal(t, Secret{File: "/run/secrets/docs_password"}, profile.BasicAuth.Password)
+	assert.Equal(t, 30*time.Second, config.Scraper.Checkpoint.Interval)
+	assert.Equal(t, 5, config.Scraper.Retry.MaxAttempts)
+	assert.Equal(t, 250*time.Millisecond, config.Scraper.Retry.BaseDelay)
+	assert.Equal(t, 30*time.Second, config.Scraper.Retry.MaxDelay)
+	assert.Equal(t, "readability", config.Scraper.Extractor)
+	require.Len(t, config.Scraper.Extractors, 1)
+	assert.Equal(t, []string{"docs.example.com", "*.example.org"}, config.Scraper.Extractors[0].Hosts)
+	assert.Equal(t, []string{".sidebar", "nav"}, config.Scraper.Extractors[0].Exclude)
 	assert.Equal(t, 500, config.Processor.ChunkSize)
+	assert.Equal(t, "recursive", config.Processor.Chunker)
+	assert.Equal(t, map[string]string{"godoc": "fixed"}, config.Processor.Chunkers)
 	assert.False(t, config.UI.Streaming)
 }
 
@@ -92,20 +152,11 @@ func TestConfigValidation(t *testing.T) {
 					VectorDim: 1536,
 					BatchSize: 100,
 				},
-				Scraper: struct {
-					MaxDepth          int      `yaml:"max_depth"`
-					RateLimit         float64  `yaml:"rate_limit"`
-					IgnorePatterns    []string `yaml:"ignore_patterns"`
-					AllowedExtensions []string `yaml:"allowed_extensions"`
-				}{
+				Scraper: ScraperConfig{
 					MaxDepth:  3,
 					RateLimit: 2.0,
 				},
-				Processor: struct {
-					ChunkSize       int  `yaml:"chunk_size"`
-					ChunkOverlap    int  `yaml:"chunk_overlap"`
-					RemoveStopwords bool `yaml:"remove_stopwords"`
-				}{
+				Processor: ProcessorConfig{
 					ChunkSize:    1000,
 					ChunkOverlap: 200,
 				},
@@ -175,3 +226,25 @@ func TestEnvironmentOverrides(t *testing.T) {
 	assert.Equal(t, "http://env-ollama:11434", config.LLM.BaseURL)
 	assert.Equal(t, "postgres://env-db:5432/test", config.Database.URL)
 }
+
+func TestProcessorValidation(t *testing.T) {
+	config := &Config{}
+	config.LLM.BaseURL = "http://localhost:11434"
+	applyDefaults(config)
+	config.Processor.Chunker = "paragraphs"
+	config.Processor.Chunkers = map[string]string{"web": "semantic", "email": "fixed", "godoc": "words"}
+	config.Processor.BreakpointPercentile = 120
+
+	var messages []string
+	for _, err := range config.Validate() {
+		if strings.HasPrefix(err.Field, "processor.") {
+			messages = append(messages, err.Error())
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"processor.chunker: unknown chunking strategy: paragraphs",
+		"processor.chunkers.email: unknown source: email",
+		"processor.chunkers.godoc: unknown chunking strategy: words",
+		"processor.breakpoint_percentile: breakpoint_percentile must be between 0 and 100",
+	}, messages)
+}
@@ -3,7 +3,10 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
+
+	"github.com/xhad/yes/internal/glob"
 )
 
 type ValidationError struct {
@@ -79,6 +82,128 @@ func (c *Config) Validate() []ValidationError {
 		})
 	}
 
+	if c.Scraper.Workers < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "scraper.workers",
+			Message: "workers must not be negative",
+		})
+	}
+
+	if c.Scraper.MaxQueueSize < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "scraper.max_queue_size",
+			Message: "max_queue_size must not be negative",
+		})
+	}
+
+	if c.Scraper.Retry.MaxAttempts < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "scraper.retry.max_attempts",
+			Message: "max_attempts must not be negative",
+		})
+	}
+
+	if c.Scraper.Retry.BaseDelay < 0 || c.Scraper.Retry.MaxDelay < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "scraper.retry",
+			Message: "retry delays must not be negative",
+		})
+	}
+
+	for _, rule := range append(append([]string{}, c.Scraper.Scope.Include...), c.Scraper.Scope.Exclude...) {
+		if err := validScopeRule(rule); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "scraper.scope",
+				Message: err.Error(),
+			})
+		}
+	}
+
+	for i, limit := range c.Scraper.HostRateLimits {
+		if len(limit.Hosts) == 0 {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("scraper.host_rate_limits[%d].hosts", i),
+				Message: "at least one host is required",
+			})
+		}
+		if limit.RateLimit <= 0 {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("scraper.host_rate_limits[%d].rate_limit", i),
+				Message: "rate_limit must be positive",
+			})
+		}
+	}
+
+	for i, profile := range c.Scraper.HTTPProfiles {
+		field := fmt.Sprintf("scraper.http_profiles[%d]", i)
+		if len(profile.Hosts) == 0 {
+			errors = append(errors, ValidationError{
+				Field:   field + ".hosts",
+				Message: "at least one host is required",
+			})
+		}
+		if !profile.BearerToken.isZero() && profile.BasicAuth.Username != "" {
+			errors = append(errors, ValidationError{
+				Field:   field,
+				Message: "bearer_token and basic_auth cannot be used together",
+			})
+		}
+		secrets := map[string]Secret{
+			"bearer_token":        profile.BearerToken,
+			"basic_auth.password": profile.BasicAuth.Password,
+			"proxy":               profile.Proxy,
+		}
+		for name, secret := range profile.Headers {
+			secrets["headers."+name] = secret
+		}
+		for name, secret := range secrets {
+			if secret.sources() > 1 {
+				errors = append(errors, ValidationError{
+					Field:   field + "." + name,
+					Message: "only one of value, env and file may be set",
+				})
+			}
+		}
+	}
+
+	switch c.Scraper.Checkpoint.Backend {
+	case "", "file", "postgres":
+	default:
+		errors = append(errors, ValidationError{
+			Field:   "scraper.checkpoint.backend",
+			Message: fmt.Sprintf("unknown checkpoint backend: %s", c.Scraper.Checkpoint.Backend),
+		})
+	}
+
+	if c.Scraper.Checkpoint.Interval < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "scraper.checkpoint.interval",
+			Message: "interval must not be negative",
+		})
+	}
+
+	if !validExtractorType(c.Scraper.Extractor) {
+		errors = append(errors, ValidationError{
+			Field:   "scraper.extractor",
+			Message: fmt.Sprintf("unknown extractor type: %s", c.Scraper.Extractor),
+		})
+	}
+
+	for i, extractor := range c.Scraper.Extractors {
+		if len(extractor.Hosts) == 0 {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("scraper.extractors[%d].hosts", i),
+				Message: "at least one host is required",
+			})
+		}
+		if !validExtractorType(extractor.Type) {
+			errors = append(errors, ValidationError{
+				Field:   fmt.Sprintf("scraper.extractors[%d].type", i),
+				Message: fmt.Sprintf("unknown extractor type: %s", extractor.Type),
+			})
+		}
+	}
+
 	// Validate extensions format
 	for _, ext := range c.Scraper.AllowedExtensions {
 		if !strings.HasPrefix(ext, ".") && ext != "" && ext != "/" {
@@ -104,6 +229,44 @@ func (c *Config) Validate() []ValidationError {
 		})
 	}
 
+	if !validChunker(c.Processor.Chunker) {
+		errors = append(errors, ValidationError{
+			Field:   "processor.chunker",
+			Message: fmt.Sprintf("unknown chunking strategy: %s", c.Processor.Chunker),
+		})
+	}
+
+	for source, chunker := range c.Processor.Chunkers {
+		switch source {
+		case "web", "warc", "filesystem", "godoc":
+		default:
+			errors = append(errors, ValidationError{
+				Field:   "processor.chunkers." + source,
+				Message: fmt.Sprintf("unknown source: %s", source),
+			})
+		}
+		if !validChunker(chunker) {
+			errors = append(errors, ValidationError{
+				Field:   "processor.chunkers." + source,
+				Message: fmt.Sprintf("unknown chunking strategy: %s", chunker),
+			})
+		}
+	}
+
+	if c.Processor.WindowSize < 0 {
+		errors = append(errors, ValidationError{
+			Field:   "processor.window_size",
+			Message: "window_size must not be negative",
+		})
+	}
+
+	if c.Processor.BreakpointPercentile < 0 || c.Processor.BreakpointPercentile > 100 {
+		errors = append(errors, ValidationError{
+			Field:   "processor.breakpoint_percentile",
+			Message: "breakpoint_percentile must be between 0 and 100",
+		})
+	}
+
 	// Validate base URL format
 	if _, err := url.Parse(c.LLM.BaseURL); err != nil {
 		errors = append(errors, ValidationError{
@@ -114,3 +277,49 @@ func (c *Config) Validate() []ValidationError {
 
 	return errors
 }
+
+// validScopeRule checks that a scope include or exclude rule parses, using
+// the same syntax as the scraper: a glob, or a regular expression after "re:".
+func validScopeRule(rule string) error {
+	if expr, ok := strings.CutPrefix(rule, "re:"); ok {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid scope rule %q: %v", rule, err)
+		}
+		return nil
+	}
+	if _, err := glob.Compile(rule); err != nil {
+		return fmt.Errorf("invalid scope rule %q: %v", rule, err)
+	}
+	return nil
+}
+
+func validExtractorType(kind string) bool {
+	switch kind {
+	case "", "selectors", "readability":
+		return true
+	}
+	return false
+}
+
+func validChunker(strategy string) bool {
+	switch strategy {
+	case "", "sentence", "fixed", "recursive", "sentence-window", "semantic":
+		return true
+	}
+	return false
+}
+
+func (s Secret) isZero() bool {
+	return s.sources() == 0
+}
+
+// sources returns how many of the ways to give a secret are used.
+func (s Secret) sources() int {
+	var n int
+	for _, source := range []string{s.Value, s.Env, s.File} {
+		if source != "" {
+			n++
+		}
+	}
+	return n
+}
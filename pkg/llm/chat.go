@@ -69,7 +69,7 @@ func (ce *ChatEngine) Chat(query string, docs []models.Document) (*llms.ContentR
 	var contextBuilder strings.Builder
 
 	for _, doc := range docs {
-		contextBuilder.WriteString(fmt.Sprintf("Source: %s\n%s\n\n", doc.URL, doc.Content))
+		contextBuilder.WriteString(fmt.Sprintf("Source: %s\n%s\n\n", Citation(doc), doc.Content))
 	}
 
 	content := []llms.MessageContent{
@@ -92,7 +92,7 @@ func (ce *ChatEngine) Chat(query string, docs []models.Document) (*llms.ContentR
 func (ce *ChatEngine) ChatStream(query string, docs []models.Document) (<-chan string, error) {
 	var contextBuilder strings.Builder
 	for _, doc := range docs {
-		contextBuilder.WriteString(fmt.Sprintf("Source: %s\n%s\n\n", doc.URL, doc.Content))
+		contextBuilder.WriteString(fmt.Sprintf("Source: %s\n%s\n\n", Citation(doc), doc.Content))
 	}
 
 	content := []llms.MessageContent{
@@ -137,9 +137,10 @@ func (ce *ChatEngine) formatSources(docs []models.Document) string {
 	seen := make(map[string]bool)
 
 	for _, doc := range docs {
-		if !seen[doc.URL] {
-			sources = append(sources, doc.URL)
-			seen[doc.URL] = true
+		// Pages of a PDF file share its URL but are cited separately
+		if cite := Citation(doc); !seen[cite] {
+			sources = append(sources, cite)
+			seen[cite] = true
 		}
 	}
 
@@ -149,3 +150,68 @@ func (ce *ChatEngine) formatSources(docs []models.Document) string {
 
 	return fmt.Sprintf("\nSources:\n%s", strings.Join(sources, "\n"))
 }
+
+// Citation describes where a document comes from: its title, URL, author
+// and publication date, as far as the page metadata provides them. Chunks
+// are titled with their heading path and pages of PDF files link to the
+// page.
+func Citation(doc models.Document) string {
+	title := metadataString(doc, "headingPath")
+	if title == "" {
+		title = strings.TrimSpace(doc.Title)
+	}
+	if title == "" {
+		title = metadataString(doc, "openGraph", "title")
+	}
+
+	link := doc.URL
+	if page := metadataInt(doc, "page"); page > 0 {
+		link = fmt.Sprintf("%s#page=%d", doc.URL, page)
+		if title != "" {
+			title = fmt.Sprintf("%s, page %d", title, page)
+		}
+	}
+
+	cite := link
+	if title != "" {
+		cite = fmt.Sprintf("%s (%s)", title, link)
+	}
+	if author := metadataString(doc, "author"); author != "" {
+		cite += ", by " + author
+	}
+	if published := metadataString(doc, "published"); published != "" {
+		cite += ", published " + published
+	}
+	return cite
+}
+
+// metadataString returns the string at path in the document metadata, or ""
+// if there is none. Nested objects are maps after a round trip through the
+// store.
+func metadataString(doc models.Document, path ...string) string {
+	var value interface{} = doc.Metadata
+	for _, key := range path {
+		switch m := value.(type) {
+		case map[string]interface{}:
+			value = m[key]
+		case map[string]string:
+			value = m[key]
+		default:
+			return ""
+		}
+	}
+	s, _ := value.(string)
+	return s
+}
+
+// metadataInt returns the number stored under key in the document metadata,
+// or 0. Numbers are float64 after a round trip through the store.
+func metadataInt(doc models.Document, key string) int {
+	switch n := doc.Metadata[key].(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
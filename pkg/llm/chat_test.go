@@ -54,3 +54,37 @@ func TestChat(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, response)
 }
+
+func TestCitation(t *testing.T) {
+	doc := models.Document{
+		URL:   "https://example.com/install",
+		Title: "Install",
+		Metadata: map[string]interface{}{
+			"author":    "Ada",
+			"published": "2024-01-02",
+		},
+	}
+	assert.Equal(t, "Install (https://example.com/install), by Ada, published 2024-01-02", llm.Citation(doc))
+
+	doc = models.Document{
+		URL:      "https://example.com/",
+		Metadata: map[string]interface{}{"openGraph": map[string]interface{}{"title": "Home"}},
+	}
+	assert.Equal(t, "Home (https://example.com/)", llm.Citation(doc))
+
+	assert.Equal(t, "https://example.com/", llm.Citation(models.Document{URL: "https://example.com/"}))
+
+	doc = models.Document{
+		URL:      "https://example.com/manual.pdf",
+		Title:    "Manual",
+		Metadata: map[string]interface{}{"page": float64(3)},
+	}
+	assert.Equal(t, "Manual, page 3 (https://example.com/manual.pdf#page=3)", llm.Citation(doc))
+
+	doc = models.Document{
+		URL:      "https://example.com/guide",
+		Title:    "Guide",
+		Metadata: map[string]interface{}{"headingPath": "Guide > Auth > Tokens"},
+	}
+	assert.Equal(t, "Guide > Auth > Tokens (https://example.com/guide)", llm.Citation(doc))
+}
@@ -2,10 +2,36 @@ package llm
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/tmc/langchaingo/llms/ollama"
 )
 
+// DefaultEmbeddingModel is the Ollama model documents are embedded with.
+const DefaultEmbeddingModel = "nomic-embed-text:latest"
+
+// embeddingInputLimits are the maximum input lengths, in tokens, of common
+// embedding models. Longer inputs are truncated by the model.
+var embeddingInputLimits = map[string]int{
+	"nomic-embed-text":       8192,
+	"mxbai-embed-large":      512,
+	"all-minilm":             256,
+	"snowflake-arctic-embed": 512,
+	"bge-m3":                 8192,
+	"bge-large":              512,
+	"text-embedding-3-small": 8191,
+	"text-embedding-3-large": 8191,
+	"text-embedding-ada-002": 8191,
+}
+
+// EmbeddingInputLimit returns the maximum number of tokens the embedding
+// model accepts, or 0 if the model is unknown. Tags such as ":latest" are
+// ignored.
+func EmbeddingInputLimit(model string) int {
+	name, _, _ := strings.Cut(model, ":")
+	return embeddingInputLimits[name]
+}
+
 // ChatConfig represents the configuration for a chat engine.
 type EmbedderConfig struct {
 	Model     string
@@ -22,7 +48,7 @@ type Embedder struct {
 func NewEmbedderWithConfig(config EmbedderConfig) Embedder {
 	// Validate and set default values for config fields if necessary
 	if config.Model == "" {
-		config.Model = "nomic-embed-text:latest" // Default Ollama model
+		config.Model = DefaultEmbeddingModel
 	}
 
 	if config.MaxTokens < 0 {
@@ -52,7 +78,7 @@ func NewEmbedderWithConfig(config EmbedderConfig) Embedder {
 func NewEmbedder() Embedder {
 
 	var config = EmbedderConfig{
-		Model:     "nomic-embed-text:latest",
+		Model:     DefaultEmbeddingModel,
 		MaxTokens: 1000,
 		BaseURL:   "http://localhost:11434",
 	}
@@ -53,3 +53,9 @@ func TestCreateEmbedding(t *testing.T) {
 		assert.Equal(t, len(embeddings[i]), 768)
 	}
 }
+
+func TestEmbeddingInputLimit(t *testing.T) {
+	assert.Equal(t, 8192, llm.EmbeddingInputLimit(llm.DefaultEmbeddingModel))
+	assert.Equal(t, 512, llm.EmbeddingInputLimit("mxbai-embed-large"))
+	assert.Equal(t, 0, llm.EmbeddingInputLimit("unknown-model:7b"))
+}
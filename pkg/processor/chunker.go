@@ -0,0 +1,353 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Chunking strategies a Processor can be configured with.
+const (
+	// StrategySentence groups whole sentences into chunks, repeating the
+	// last sentences of a chunk at the start of the next.
+	StrategySentence = "sentence"
+	// StrategyFixed cuts chunks of a fixed number of tokens between words,
+	// regardless of sentences.
+	StrategyFixed = "fixed"
+	// StrategyRecursive splits on paragraphs, then sentences, then words,
+	// going down a level only for pieces too long for a chunk.
+	StrategyRecursive = "recursive"
+	// StrategySentenceWindow makes a chunk of every sentence with the
+	// sentences around it.
+	StrategySentenceWindow = "sentence-window"
+	// StrategySemantic splits where the meaning of adjacent sentences
+	// drifts apart, as measured by their embeddings.
+	StrategySemantic = "semantic"
+)
+
+// Chunker splits the text of a document section into chunks of at most size
+// tokens. Paragraphs in text are separated by blank lines.
+type Chunker interface {
+	Chunk(text string, size int) ([]string, error)
+}
+
+// newChunker returns the Chunker for the configured strategy.
+func newChunker(config ProcessorConfig) (Chunker, error) {
+	tokenizer, overlap := config.Tokenizer, config.ChunkOverlap
+
+	switch config.Strategy {
+	case "", StrategySentence:
+		return sentenceChunker{tokenizer: tokenizer, overlap: overlap}, nil
+	case StrategyFixed:
+		return fixedChunker{tokenizer: tokenizer, overlap: overlap}, nil
+	case StrategyRecursive:
+		return recursiveChunker{tokenizer: tokenizer, overlap: overlap}, nil
+	case StrategySentenceWindow:
+		window := config.WindowSize
+		if window == 0 {
+			window = 1
+		}
+		return windowChunker{tokenizer: tokenizer, window: window}, nil
+	case StrategySemantic:
+		if config.Embedder == nil {
+			return nil, fmt.Errorf("the %s strategy needs an embedder", StrategySemantic)
+		}
+		percentile := config.BreakpointPercentile
+		if percentile == 0 {
+			percentile = 95
+		}
+		if percentile < 0 || percentile > 100 {
+			return nil, fmt.Errorf("breakpoint percentile must be between 0 and 100")
+		}
+		return semanticChunker{tokenizer: tokenizer, overlap: overlap, embedder: config.Embedder, percentile: percentile}, nil
+	}
+	return nil, fmt.Errorf("unknown chunking strategy: %s", config.Strategy)
+}
+
+// sentenceChunker implements StrategySentence.
+type sentenceChunker struct {
+	tokenizer Tokenizer
+	overlap   int
+}
+
+// Chunk groups the sentences of text into chunks. Each chunk after the first
+// starts with the last whole sentences of the previous one, up to the
+// overlap, so an overlap never cuts a sentence or a multi-byte character.
+func (c sentenceChunker) Chunk(text string, size int) ([]string, error) {
+	return texts(groupUnits(sentenceUnits(c.tokenizer, text, size), size, c.overlap, " ")), nil
+}
+
+// fixedChunker implements StrategyFixed.
+type fixedChunker struct {
+	tokenizer Tokenizer
+	overlap   int
+}
+
+// Chunk groups the words of text into chunks of size tokens, overlapping by
+// whole words.
+func (c fixedChunker) Chunk(text string, size int) ([]string, error) {
+	return texts(groupUnits(wordUnits(c.tokenizer, text, size), size, c.overlap, " ")), nil
+}
+
+// recursiveChunker implements StrategyRecursive.
+type recursiveChunker struct {
+	tokenizer Tokenizer
+	overlap   int
+}
+
+// separator is a level of recursive splitting.
+type separator struct {
+	split func(string) []string
+	join  string
+}
+
+// recursiveSeparators are the levels of recursive splitting, coarsest first.
+var recursiveSeparators = []separator{
+	{split: splitParagraphs, join: "\n\n"},
+	{split: splitIntoSentences, join: " "},
+	{split: strings.Fields, join: " "},
+	{split: splitRunes, join: ""},
+}
+
+func (c recursiveChunker) Chunk(text string, size int) ([]string, error) {
+	return c.split(text, size, 0), nil
+}
+
+// split splits text at the given level of recursiveSeparators and groups
+// the pieces into chunks. Pieces too long for a chunk are split at the next
+// level, and their chunks kept as they are.
+func (c recursiveChunker) split(text string, size, level int) []string {
+	sep := recursiveSeparators[level]
+
+	var (
+		chunks []string
+		run    []unit
+	)
+	flush := func() {
+		chunks = append(chunks, texts(groupUnits(run, size, c.overlap, sep.join))...)
+		run = nil
+	}
+	for _, piece := range sep.split(text) {
+		n := c.tokenizer.Count(piece)
+		if n <= size || level == len(recursiveSeparators)-1 {
+			run = append(run, unit{text: piece, tokens: n})
+			continue
+		}
+		flush()
+		chunks = append(chunks, c.split(piece, size, level+1)...)
+	}
+	flush()
+	return chunks
+}
+
+// windowChunker implements StrategySentenceWindow.
+type windowChunker struct {
+	tokenizer Tokenizer
+	window    int
+}
+
+// Chunk returns a chunk for every sentence of text, made of the sentence and
+// up to window sentences on each side. The farthest sentences are left out
+// of windows that do not fit in size tokens. Windows repeating the previous
+// one, as in texts shorter than a window, are skipped.
+func (c windowChunker) Chunk(text string, size int) ([]string, error) {
+	units := sentenceUnits(c.tokenizer, text, size)
+
+	var chunks []string
+	for i := range units {
+		start, end := max(i-c.window, 0), min(i+c.window+1, len(units))
+		tokens := sumTokens(units[start:end])
+		for tokens > size {
+			// Drop the farther of the two ends
+			if i-start >= end-1-i {
+				tokens -= units[start].tokens
+				start++
+			} else {
+				end--
+				tokens -= units[end].tokens
+			}
+		}
+
+		chunk := joinUnits(units[start:end], " ")
+		if len(chunks) == 0 || chunks[len(chunks)-1] != chunk {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
+// unit is a piece of text chunks are built from, such as a sentence or a
+// word, with its token count.
+type unit struct {
+	text   string
+	tokens int
+	sep    string // break before the unit in the text, used instead of the join separator
+}
+
+// groupUnits groups consecutive units into chunks of at most size tokens,
+// joined by sep. Each chunk after the first starts with the last units of
+// the previous one that fit in overlap tokens. The token count of a chunk
+// is taken as the sum of its units', which is at least its own.
+func groupUnits(units []unit, size, overlap int, sep string) []unit {
+	var (
+		chunks  []unit
+		current []unit
+		tokens  int
+	)
+	for _, u := range units {
+		if tokens+u.tokens > size && len(current) > 0 {
+			chunks = append(chunks, unit{text: joinUnits(current, sep), tokens: tokens})
+
+			// Start the next chunk with the units that fit the overlap
+			keep, kept := len(current), 0
+			for keep > 0 {
+				n := current[keep-1].tokens
+				if kept+n > overlap || kept+n+u.tokens > size {
+					break
+				}
+				keep--
+				kept += n
+			}
+			current = append([]unit(nil), current[keep:]...)
+			tokens = kept
+		}
+
+		current = append(current, u)
+		tokens += u.tokens
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, unit{text: joinUnits(current, sep), tokens: tokens})
+	}
+	return chunks
+}
+
+// sentenceUnits returns the sentences of text, with sentences longer than
+// size tokens split by splitLong. Sentences starting a paragraph or a line
+// keep the break before them, so lists and tables stay apart when joined.
+func sentenceUnits(tokenizer Tokenizer, text string, size int) []unit {
+	var units []unit
+	for i, paragraph := range splitParagraphs(text) {
+		end := 0
+		for j, s := range splitIntoSentences(paragraph) {
+			start := end + strings.Index(paragraph[end:], s)
+			var sep string
+			switch {
+			case j == 0 && i > 0:
+				sep = "\n\n"
+			case strings.Contains(paragraph[end:start], "\n"):
+				sep = "\n"
+			}
+			end = start + len(s)
+
+			pieces := []unit{{text: s, tokens: tokenizer.Count(s)}}
+			if pieces[0].tokens > size {
+				pieces = splitLong(tokenizer, s, size)
+			}
+			pieces[0].sep = sep
+			units = append(units, pieces...)
+		}
+	}
+	return units
+}
+
+// splitLong splits text between words into pieces of at most size tokens,
+// and between characters for words longer than that.
+func splitLong(tokenizer Tokenizer, text string, size int) []unit {
+	return groupUnits(wordUnits(tokenizer, text, size), size, 0, " ")
+}
+
+// wordUnits returns the words of text. Words longer than size tokens are
+// split between characters into pieces of at most size tokens, counted as
+// the sum of their characters'.
+func wordUnits(tokenizer Tokenizer, text string, size int) []unit {
+	var units []unit
+	for _, word := range strings.Fields(text) {
+		if n := tokenizer.Count(" " + word); n <= size {
+			units = append(units, unit{text: word, tokens: n})
+			continue
+		}
+		var runes []unit
+		for _, r := range word {
+			runes = append(runes, unit{text: string(r), tokens: tokenizer.Count(string(r))})
+		}
+		units = append(units, groupUnits(runes, size, 0, "")...)
+	}
+	return units
+}
+
+// splitIntoSentences splits text after sentence-ending punctuation followed
+// by whitespace, except inside `inline code`. The end of a paragraph also
+// ends a sentence.
+func splitIntoSentences(text string) []string {
+	// Basic sentence splitting - can be improved with NLP libraries
+	var sentences []string
+	for _, paragraph := range splitParagraphs(text) {
+		start, code := 0, false
+		for i := 0; i < len(paragraph)-1; i++ {
+			switch paragraph[i] {
+			case '`':
+				code = !code
+			case '.', '!', '?':
+				if next := paragraph[i+1]; !code && (next == ' ' || next == '\n') {
+					sentences = append(sentences, strings.TrimSpace(paragraph[start:i+1]))
+					start = i + 1
+				}
+			}
+		}
+		if rest := strings.TrimSpace(paragraph[start:]); rest != "" {
+			sentences = append(sentences, rest)
+		}
+	}
+	return sentences
+}
+
+// splitParagraphs splits text on blank lines.
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
+	}
+	return paragraphs
+}
+
+func splitRunes(text string) []string {
+	runes := make([]string, 0, len(text))
+	for _, r := range text {
+		runes = append(runes, string(r))
+	}
+	return runes
+}
+
+func texts(units []unit) []string {
+	texts := make([]string, len(units))
+	for i, u := range units {
+		texts[i] = u.text
+	}
+	return texts
+}
+
+// joinUnits joins the text of units with sep, or with the break a unit
+// keeps before it.
+func joinUnits(units []unit, sep string) string {
+	var b strings.Builder
+	for i, u := range units {
+		if i > 0 {
+			if u.sep != "" {
+				b.WriteString(u.sep)
+			} else {
+				b.WriteString(sep)
+			}
+		}
+		b.WriteString(u.text)
+	}
+	return b.String()
+}
+
+func sumTokens(units []unit) int {
+	n := 0
+	for _, u := range units {
+		n += u.tokens
+	}
+	return n
+}
@@ -0,0 +1,113 @@
+package processor_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
+	"github.com/xhad/yes/pkg/processor"
+)
+
+// chunk processes text with the given configuration, counting words as
+// tokens, and returns its chunks.
+func chunk(t *testing.T, config processor.ProcessorConfig, text string) []string {
+	t.Helper()
+	config.Tokenizer = words
+	config.MinChunkLength = 1
+
+	p, err := processor.New(config)
+	require.NoError(t, err)
+	docs, err := p.Process([]models.Document{{Content: text}})
+	require.NoError(t, err)
+	return docs[0].Chunks
+}
+
+func TestFixedChunker(t *testing.T) {
+	chunks := chunk(t, processor.ProcessorConfig{Strategy: processor.StrategyFixed, ChunkSize: 3, ChunkOverlap: 1},
+		"One two three. Four five six seven.")
+	assert.Equal(t, []string{"One two three.", "three. Four five", "five six seven."}, chunks)
+}
+
+func TestRecursiveChunker(t *testing.T) {
+	chunks := chunk(t, processor.ProcessorConfig{Strategy: processor.StrategyRecursive, ChunkSize: 8, ChunkOverlap: 1},
+		"Intro.\n\nPara one is short.\n\nPara two has two sentences. It is long enough to split.\n\nEnd.")
+	assert.Equal(t, []string{
+		"Intro.\n\nPara one is short.",
+		"Para two has two sentences.",
+		"It is long enough to split.",
+		"End.",
+	}, chunks)
+}
+
+func TestSentenceWindowChunker(t *testing.T) {
+	config := processor.ProcessorConfig{Strategy: processor.StrategySentenceWindow, ChunkSize: 100, ChunkOverlap: 1}
+	assert.Equal(t, []string{
+		"A one. B two.",
+		"A one. B two. C three.",
+		"B two. C three. D four.",
+		"C three. D four.",
+	}, chunk(t, config, "A one. B two. C three. D four."))
+
+	// Windows too long for a chunk lose their farthest sentences
+	config.ChunkSize = 5
+	assert.Equal(t, []string{
+		"A one. B two.",
+		"B two. C three.",
+		"C three. D four.",
+	}, chunk(t, config, "A one. B two. C three. D four."))
+
+	config.ChunkSize, config.WindowSize = 100, 5
+	assert.Equal(t, []string{"A one. B two."}, chunk(t, config, "A one. B two."))
+}
+
+// topicEmbedder embeds sentences about cats and dogs as orthogonal vectors.
+type topicEmbedder struct{}
+
+func (topicEmbedder) CreateEmbedding(_ context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		if strings.Contains(text, "Cats") {
+			embeddings[i] = []float32{1, 0.1}
+		} else {
+			embeddings[i] = []float32{0.1, 1}
+		}
+	}
+	return embeddings, nil
+}
+
+func TestSemanticChunker(t *testing.T) {
+	config := processor.ProcessorConfig{
+		Strategy:     processor.StrategySemantic,
+		ChunkSize:    100,
+		ChunkOverlap: 1,
+		Embedder:     topicEmbedder{},
+	}
+	assert.Equal(t, []string{"Cats purr. Cats nap.", "Dogs bark. Dogs fetch. Dogs run."},
+		chunk(t, config, "Cats purr. Cats nap. Dogs bark. Dogs fetch. Dogs run."))
+
+	// Groups longer than a chunk are split on sentences
+	config.ChunkSize = 4
+	assert.Equal(t, []string{"Cats purr. Cats nap.", "Dogs bark. Dogs fetch.", "Dogs run."},
+		chunk(t, config, "Cats purr. Cats nap. Dogs bark. Dogs fetch. Dogs run."))
+}
+
+type upperChunker struct{}
+
+func (upperChunker) Chunk(text string, size int) ([]string, error) {
+	return []string{strings.ToUpper(text)}, nil
+}
+
+func TestCustomChunker(t *testing.T) {
+	assert.Equal(t, []string{"SOME TEXT."}, chunk(t, processor.ProcessorConfig{Chunker: upperChunker{}}, "Some text."))
+}
+
+func TestChunkerErrors(t *testing.T) {
+	_, err := processor.New(processor.ProcessorConfig{Strategy: "paragraphs", Tokenizer: words})
+	assert.EqualError(t, err, "unknown chunking strategy: paragraphs")
+
+	_, err = processor.New(processor.ProcessorConfig{Strategy: processor.StrategySemantic, Tokenizer: words})
+	assert.EqualError(t, err, "the semantic strategy needs an embedder")
+}
@@ -0,0 +1,210 @@
+package processor
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/xhad/yes/internal/models"
+)
+
+// segment is a run of prose or a code block in the body of a section.
+type segment struct {
+	prose string
+	code  *codeBlock
+}
+
+// codeBlock is a fenced or <pre> code block, which chunking keeps whole and
+// whitespace intact.
+type codeBlock struct {
+	language string
+	text     string // the block as fenced Markdown
+}
+
+var (
+	preOpen  = regexp.MustCompile(`(?i)^ {0,3}<pre[\s>]`)
+	preClose = regexp.MustCompile(`(?i)</pre>`)
+	preBlock = regexp.MustCompile(`(?is)<pre\b([^>]*)>\s*(?:<code\b([^>]*)>)?(.*?)(?:</code>\s*)?</pre>`)
+	htmlTag  = regexp.MustCompile(`<[^>]*>`)
+
+	// codeLanguageClass matches the class naming the language of a code
+	// block, as written by highlighters.
+	codeLanguageClass = regexp.MustCompile(`\b(?:language|lang|highlight)-([\w+#-]+)`)
+)
+
+// splitCode splits the body of a section into prose and the fenced and
+// <pre> code blocks between it. Fenced blocks are kept as written; <pre>
+// blocks are turned into fenced ones, with their tags removed and entities
+// unescaped. An unclosed fence runs to the end of the body, as in Markdown.
+func splitCode(body string) []segment {
+	var (
+		segments []segment
+		prose    []string
+	)
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(prose, "\n")); text != "" {
+			segments = append(segments, segment{prose: text})
+		}
+		prose = nil
+	}
+
+	lines := strings.Split(body, "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if m := codeFence.FindStringSubmatch(line); m != nil {
+			end := i + 1
+			for end < len(lines) && !closesFence(lines[end], m[1]) {
+				end++
+			}
+			end = min(end, len(lines)-1)
+
+			flush()
+			segments = append(segments, segment{code: &codeBlock{
+				language: fenceLanguage(line[len(m[0]):]),
+				text:     strings.Join(lines[i:end+1], "\n"),
+			}})
+			i = end
+			continue
+		}
+
+		if preOpen.MatchString(line) {
+			end := i
+			for end < len(lines)-1 && !preClose.MatchString(lines[end]) {
+				end++
+			}
+			block := strings.Join(lines[i:end+1], "\n")
+			if loc := preBlock.FindStringSubmatchIndex(block); loc != nil {
+				m := preBlock.FindStringSubmatch(block)
+				prose = append(prose, block[:loc[0]])
+				flush()
+				segments = append(segments, segment{code: preCode(m[1], m[2], m[3])})
+				prose = append(prose, block[loc[1]:])
+				i = end
+				continue
+			}
+		}
+		prose = append(prose, line)
+	}
+	flush()
+	return segments
+}
+
+// fenceLanguage returns the language named by the info string of a fence,
+// such as "go" in "```go title=main.go".
+func fenceLanguage(info string) string {
+	fields := strings.Fields(info)
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ToLower(strings.Trim(fields[0], "{}."))
+}
+
+// preCode converts a <pre> block to a fenced code block, taking its language
+// from a class on its <code> child or on the <pre> itself.
+func preCode(preAttrs, codeAttrs, inner string) *codeBlock {
+	code := strings.Trim(html.UnescapeString(htmlTag.ReplaceAllString(inner, "")), "\n")
+
+	var language string
+	for _, attrs := range []string{codeAttrs, preAttrs} {
+		if m := codeLanguageClass.FindStringSubmatch(attrs); m != nil {
+			language = strings.ToLower(m[1])
+			break
+		}
+	}
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return &codeBlock{language: language, text: fence + language + "\n" + code + "\n" + fence}
+}
+
+// sectionChunk is a chunk of a section along with the code blocks it holds.
+type sectionChunk struct {
+	text     string
+	code     models.ChunkCode
+	codeOnly bool
+	segment  int // index of the segment the chunk starts with
+}
+
+// chunkSection chunks the body of a section, keeping code blocks whole. The
+// prose between code blocks is chunked with the configured Chunker. A code
+// block joins the chunk before it when they fit in size tokens together,
+// and otherwise starts a chunk; either way, the first chunk of the prose
+// after it joins too if it fits. Blocks longer than size get a chunk of
+// their own, whole, even though the embedding model may only see their
+// beginning. Chunks holding nothing but code are given the paragraphs
+// around their block as context.
+func (p *Processor) chunkSection(body string, size int) ([]sectionChunk, error) {
+	segments := splitCode(body)
+
+	var chunks []sectionChunk
+	joinable := -1 // index of the chunk of the last code block, which the next prose may join
+	for i, seg := range segments {
+		if seg.code == nil {
+			texts, err := p.chunk(p.cleanText(seg.prose), size)
+			if err != nil {
+				return nil, err
+			}
+			for j, text := range texts {
+				if j == 0 && joinable >= 0 {
+					if joined := chunks[joinable].text + "\n\n" + text; p.config.Tokenizer.Count(joined) <= size {
+						chunks[joinable].text = joined
+						chunks[joinable].codeOnly = false
+						continue
+					}
+				}
+				chunks = append(chunks, sectionChunk{text: text, segment: i})
+			}
+			joinable = -1
+			continue
+		}
+
+		block := seg.code
+		if last := len(chunks) - 1; last >= 0 {
+			if joined := chunks[last].text + "\n\n" + block.text; p.config.Tokenizer.Count(joined) <= size {
+				chunks[last].text = joined
+				addCode(&chunks[last].code, block)
+				joinable = last
+				continue
+			}
+		}
+		chunk := sectionChunk{text: block.text, codeOnly: true, segment: i}
+		addCode(&chunk.code, block)
+		chunks = append(chunks, chunk)
+		joinable = -1
+		if p.config.Tokenizer.Count(block.text) <= size {
+			joinable = len(chunks) - 1
+		}
+	}
+
+	for i := range chunks {
+		if chunks[i].codeOnly {
+			chunks[i].code.Context = codeContext(segments, chunks[i].segment)
+		}
+	}
+	return chunks, nil
+}
+
+// addCode records block in the code of a chunk.
+func addCode(code *models.ChunkCode, block *codeBlock) {
+	code.Blocks++
+	if block.language != "" && !contains(code.Languages, block.language) {
+		code.Languages = append(code.Languages, block.language)
+	}
+}
+
+// codeContext returns the prose around the code block segments[i]: the
+// last paragraph before it and the first after it, within its section.
+func codeContext(segments []segment, i int) string {
+	var context []string
+	if i > 0 && segments[i-1].code == nil {
+		paragraphs := splitParagraphs(segments[i-1].prose)
+		context = append(context, strings.Join(strings.Fields(paragraphs[len(paragraphs)-1]), " "))
+	}
+	if i+1 < len(segments) && segments[i+1].code == nil {
+		paragraphs := splitParagraphs(segments[i+1].prose)
+		context = append(context, strings.Join(strings.Fields(paragraphs[0]), " "))
+	}
+	return strings.Join(context, "\n\n")
+}
@@ -0,0 +1,84 @@
+package processor_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
+	"github.com/xhad/yes/pkg/processor"
+)
+
+const codeMarkdown = "Call the client first. It needs a key.\n\n" +
+	"```go\nc := client.New(key)\n\n// Ping it. Then close it.\nc.Ping()\n```\n\n" +
+	"The call returns an error."
+
+const goBlock = "```go\nc := client.New(key)\n\n// Ping it. Then close it.\nc.Ping()\n```"
+
+func processCode(t *testing.T, size int, markdown string) models.ProcessedDocument {
+	t.Helper()
+	p, err := processor.New(processor.ProcessorConfig{
+		ChunkSize:      size,
+		ChunkOverlap:   2,
+		MinChunkLength: 1,
+		Tokenizer:      words,
+	})
+	require.NoError(t, err)
+	docs, err := p.Process([]models.Document{{Markdown: markdown}})
+	require.NoError(t, err)
+	return docs[0]
+}
+
+func TestCodeBlockInChunk(t *testing.T) {
+	doc := processCode(t, 20, codeMarkdown)
+
+	// The block joins the prose before it, whitespace and all
+	assert.Equal(t, []string{
+		"Call the client first. It needs a key.\n\n" + goBlock,
+		"The call returns an error.",
+	}, doc.Chunks)
+	assert.Equal(t, []models.ChunkCode{{Blocks: 1, Languages: []string{"go"}}, {}}, doc.Code)
+}
+
+func TestLargeCodeBlock(t *testing.T) {
+	doc := processCode(t, 8, codeMarkdown)
+
+	assert.Equal(t, []string{
+		"Call the client first. It needs a key.",
+		goBlock,
+		"The call returns an error.",
+	}, doc.Chunks)
+	assert.Equal(t, models.ChunkCode{
+		Blocks:    1,
+		Languages: []string{"go"},
+		Context:   "Call the client first. It needs a key.\n\nThe call returns an error.",
+	}, doc.Code[1])
+	assert.Contains(t, doc.Normalized[1], "The call returns an error.")
+}
+
+func TestConsecutiveCodeBlocks(t *testing.T) {
+	first := "```text\nShow it with:\n```go fenced\n```"
+	second := "````go\nfmt.Println(\"```\")\n`````"
+	doc := processCode(t, 512, "Two examples:\n\n"+first+"\n"+second+"\n\n# Next\n\nMore prose.")
+
+	// A fence with an info string does not close a block, and a closing
+	// fence may be longer than the opening one
+	assert.Equal(t, []string{"Two examples:\n\n" + first + "\n\n" + second, "More prose."}, doc.Chunks)
+	assert.Equal(t, models.ChunkCode{Blocks: 2, Languages: []string{"text", "go"}}, doc.Code[0])
+	assert.Equal(t, []string{"", "Next"}, doc.HeadingPaths)
+}
+
+func TestPreCodeBlock(t *testing.T) {
+	doc := processCode(t, 512, "Compare them:\n\n"+
+		"<pre class=\"highlight\"><code class=\"language-python\">a = 1\n# not a heading\nprint(a &lt; 2)</code></pre>\n\n"+
+		"It prints True.")
+
+	assert.Equal(t, []string{"Compare them:\n\n```python\na = 1\n# not a heading\nprint(a < 2)\n```\n\nIt prints True."}, doc.Chunks)
+	assert.Equal(t, []string{""}, doc.HeadingPaths)
+	assert.Equal(t, []string{"python"}, doc.Code[0].Languages)
+}
+
+func TestInlineCodeSentences(t *testing.T) {
+	chunks := chunk(t, processor.ProcessorConfig{ChunkSize: 5, ChunkOverlap: 1}, "Run `make test. ok` now. Done.")
+	assert.Equal(t, []string{"Run `make test. ok` now.", "Done."}, chunks)
+}
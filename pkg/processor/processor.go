@@ -1,54 +1,149 @@
 package processor
 
 import (
+	"fmt"
+	"regexp"
 	"strings"
+	"unicode"
 
 	"github.com/xhad/yes/internal/models"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
 )
 
 type ProcessorConfig struct {
-	ChunkSize          int
-	ChunkOverlap       int
-	MinChunkLength     int
-	RemoveStopwords    bool
-	CustomStopwords    []string
+	ChunkSize      int       // maximum tokens in a chunk, with its heading path
+	ChunkOverlap   int       // tokens repeated from the previous chunk
+	MinChunkLength int       // tokens; shorter chunks are dropped unless a section has no other
+	Tokenizer      Tokenizer // counts tokens, tiktoken's cl100k_base if nil
+	EmbeddingLimit int       // input limit of the embedding model in tokens, unchecked if 0
+
+	// Steps producing the normalized form of chunks, which is embedded and
+	// matched against keywords. Chunks themselves keep the original text.
+	Lowercase        bool
+	FoldAccents      bool // "café" becomes "cafe"
+	StripPunctuation bool
+	RemoveStopwords  bool
+	CustomStopwords  []string
+
+	// Strategy is the chunking strategy, one of the Strategy constants, and
+	// StrategySentence if empty. Chunker replaces it with a custom one.
+	Strategy string
+	Chunker  Chunker
+
+	// WindowSize is the number of sentences on each side of the sentence of
+	// a sentence-window chunk, 1 if zero.
+	WindowSize int
+
+	// Embedder embeds sentences for the semantic strategy, which splits
+	// where the distance between adjacent sentences is above the
+	// BreakpointPercentile of the document's distances, 95 if zero.
+	Embedder             Embedder
+	BreakpointPercentile float64
+
+	// Deprecated: Chunks keep their case and line breaks. Set Lowercase to
+	// lowercase their normalized form.
 	PreserveLineBreaks bool
 }
 
 type Processor struct {
 	config ProcessorConfig
+	err    error // invalid configuration, returned by Process
 }
 
+// NewWithConfig returns a processor for config.
+//
+// Deprecated: Use New, which reports an invalid configuration straight away.
+// A processor returned by NewWithConfig reports it from Process instead.
 func NewWithConfig(config ProcessorConfig) Processor {
+	p, err := New(config)
+	if err != nil {
+		return Processor{config: config, err: err}
+	}
+	return p
+}
+
+// New returns a processor for config, with defaults for its zero fields, or
+// an error if config is invalid.
+func New(config ProcessorConfig) (Processor, error) {
 	if config.ChunkSize == 0 {
-		config.ChunkSize = 1000
+		config.ChunkSize = 512
 	}
 	if config.ChunkOverlap == 0 {
-		config.ChunkOverlap = 200
+		config.ChunkOverlap = 64
 	}
 	if config.MinChunkLength == 0 {
-		config.MinChunkLength = 100
+		config.MinChunkLength = 25
+	}
+	if config.ChunkOverlap >= config.ChunkSize {
+		return Processor{}, fmt.Errorf("chunk overlap of %d tokens must be less than the chunk size of %d", config.ChunkOverlap, config.ChunkSize)
+	}
+	if config.EmbeddingLimit > 0 && config.ChunkSize > config.EmbeddingLimit {
+		return Processor{}, fmt.Errorf("chunk size of %d tokens exceeds the %d token input limit of the embedding model", config.ChunkSize, config.EmbeddingLimit)
+	}
+	if config.Tokenizer == nil {
+		config.Tokenizer = defaultTokenizer()
+	}
+	if config.Chunker == nil {
+		chunker, err := newChunker(config)
+		if err != nil {
+			return Processor{}, err
+		}
+		config.Chunker = chunker
 	}
 
 	return Processor{
 		config: config,
-	}
+	}, nil
 }
 
 func (p *Processor) Process(docs []models.Document) ([]models.ProcessedDocument, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
 	var processed []models.ProcessedDocument
 
 	for _, doc := range docs {
-		// Clean the content
-		cleanContent := p.cleanText(doc.Content)
+		// Split on headings first, so no chunk spans two sections
+		sections := []section{{body: doc.Content}}
+		if doc.Markdown != "" {
+			sections = splitSections(doc.Markdown)
+		}
 
-		// Split into chunks
-		chunks := p.splitIntoChunks(cleanContent)
+		var (
+			chunks, paths, normalized []string
+			code                      []models.ChunkCode
+		)
+		for _, section := range sections {
+			path := headingPath(doc.Title, section.path)
+			sectionChunks, err := p.chunkSection(section.body, p.sectionSize(path))
+			if err != nil {
+				return nil, fmt.Errorf("failed to chunk %s: %v", doc.URL, err)
+			}
+			for _, chunk := range sectionChunks {
+				chunks = append(chunks, chunk.text)
+				paths = append(paths, path)
+				code = append(code, chunk.code)
+
+				// Code alone says little about what it does, so its context
+				// is matched along with it
+				text := chunk.text
+				if chunk.code.Context != "" {
+					text = chunk.code.Context + "\n\n" + text
+				}
+				normalized = append(normalized, p.Normalize(text))
+			}
+		}
 
 		// Create processed document
 		processedDoc := models.ProcessedDocument{
-			Document: doc,
-			Chunks:   chunks,
+			Document:     doc,
+			Chunks:       chunks,
+			HeadingPaths: paths,
+			Normalized:   normalized,
+			Code:         code,
 		}
 		processed = append(processed, processedDoc)
 	}
@@ -56,102 +151,136 @@ func (p *Processor) Process(docs []models.Document) ([]models.ProcessedDocument,
 	return processed, nil
 }
 
-func (p *Processor) cleanText(text string) string {
-	// Convert to lowercase if needed
-	if !p.config.PreserveLineBreaks {
-		text = strings.ToLower(text)
+// chunk splits the text of a section with the configured Chunker and drops
+// chunks shorter than MinChunkLength, but keeps the only chunk of a short
+// section.
+func (p *Processor) chunk(text string, size int) ([]string, error) {
+	if text == "" {
+		return nil, nil
 	}
-
-	// Replace multiple spaces with single space
-	text = strings.Join(strings.Fields(text), " ")
-
-	// Remove stopwords if configured
-	if p.config.RemoveStopwords {
-		text = p.removeStopwords(text)
+	chunks, err := p.config.Chunker.Chunk(text, size)
+	if err != nil || len(chunks) <= 1 {
+		return chunks, err
 	}
 
-	return strings.TrimSpace(text)
+	var kept []string
+	for _, chunk := range chunks {
+		if p.config.Tokenizer.Count(chunk) >= p.config.MinChunkLength {
+			kept = append(kept, chunk)
+		}
+	}
+	return kept, nil
 }
 
-func (p *Processor) splitIntoChunks(text string) []string {
-	var chunks []string
-
-	// Split by sentences first
-	sentences := p.splitIntoSentences(text)
+// headingPath joins the headings of a section into a path such as
+// "Guide > Auth > Tokens". It starts with the document title unless the
+// outermost heading repeats it.
+func headingPath(title string, headings []string) string {
+	if title = strings.TrimSpace(title); title != "" && (len(headings) == 0 || headings[0] != title) {
+		headings = append([]string{title}, headings...)
+	}
+	return strings.Join(headings, headingSeparator)
+}
 
-	currentChunk := strings.Builder{}
+// sectionSize is the number of tokens left for the chunks of a section once
+// its heading path is prepended, as it is when the chunks are embedded. It
+// never goes below half the chunk size, so very long paths do not reduce
+// chunks to a few words.
+func (p *Processor) sectionSize(path string) int {
+	size := p.config.ChunkSize
+	if path != "" {
+		size -= p.config.Tokenizer.Count(models.EmbeddingText(path, ""))
+	}
+	return max(size, p.config.ChunkSize/2)
+}
 
-	for _, sentence := range sentences {
-		// If adding this sentence would exceed chunk size
-		if currentChunk.Len()+len(sentence) > p.config.ChunkSize {
-			// Save current chunk if it meets minimum length
-			if currentChunk.Len() >= p.config.MinChunkLength {
-				chunks = append(chunks, currentChunk.String())
-			}
+var (
+	// paragraphBreak matches the blank lines between paragraphs.
+	paragraphBreak = regexp.MustCompile(`\n[ \t\r]*\n\s*`)
+	// spaceRun matches the spaces and tabs between words.
+	spaceRun = regexp.MustCompile(`[ \t]+`)
+)
 
-			// Start new chunk with overlap
-			if p.config.ChunkOverlap > 0 && currentChunk.Len() > p.config.ChunkOverlap {
-				// Get the last few characters for overlap
-				text := currentChunk.String()
-				lastPart := text[len(text)-p.config.ChunkOverlap:]
-				currentChunk.Reset()
-				currentChunk.WriteString(lastPart)
-			} else {
-				currentChunk.Reset()
+// cleanText normalizes whitespace, keeping paragraphs apart with a blank
+// line for the strategies that split on them. Runs of spaces and tabs are
+// collapsed within each line, but line breaks and indentation are kept, so
+// lists and tables keep their shape. The text is otherwise left as it is,
+// since chunks are quoted to the LLM.
+func (p *Processor) cleanText(text string) string {
+	var paragraphs []string
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		var lines []string
+		for _, line := range strings.Split(paragraph, "\n") {
+			line = strings.TrimRight(line, " \t\r")
+			indent := len(line) - len(strings.TrimLeft(line, " \t"))
+			if line = line[:indent] + spaceRun.ReplaceAllString(line[indent:], " "); strings.TrimSpace(line) != "" {
+				lines = append(lines, line)
 			}
 		}
-
-		currentChunk.WriteString(sentence)
-		currentChunk.WriteString(" ")
-	}
-
-	// Add the last chunk if it meets minimum length
-	if currentChunk.Len() >= p.config.MinChunkLength {
-		chunks = append(chunks, strings.TrimSpace(currentChunk.String()))
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+		}
 	}
 
-	return chunks
+	return strings.Join(paragraphs, "\n\n")
 }
 
-func (p *Processor) splitIntoSentences(text string) []string {
-	// Basic sentence splitting - can be improved with NLP libraries
-	sentenceEnders := []string{". ", "! ", "? ", ".\n", "!\n", "?\n"}
-	var sentences []string
-
-	current := strings.Builder{}
-
-	for i := 0; i < len(text); i++ {
-		current.WriteByte(text[i])
-
-		// Check for sentence endings
-		for _, ender := range sentenceEnders {
-			if strings.HasSuffix(current.String(), ender) {
-				sentences = append(sentences, strings.TrimSpace(current.String()))
-				current.Reset()
-				break
+// Normalize returns the normalized form of text, produced by the enabled
+// normalization steps. Questions should be normalized the same way as the
+// chunks they are matched against.
+func (p *Processor) Normalize(text string) string {
+	if p.config.Lowercase {
+		text = strings.ToLower(text)
+	}
+	if p.config.FoldAccents {
+		text = foldAccents(text)
+	}
+	if p.config.StripPunctuation {
+		text = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) || unicode.IsSymbol(r) {
+				return ' '
 			}
-		}
+			return r
+		}, text)
 	}
 
-	// Add any remaining text
-	if current.Len() > 0 {
-		sentences = append(sentences, strings.TrimSpace(current.String()))
+	var paragraphs []string
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		// Remove stopwords if configured
+		if p.config.RemoveStopwords {
+			paragraph = p.removeStopwords(paragraph)
+		}
+		if paragraph = strings.Join(strings.Fields(paragraph), " "); paragraph != "" {
+			paragraphs = append(paragraphs, paragraph)
+		}
 	}
+	return strings.Join(paragraphs, "\n\n")
+}
 
-	return sentences
+// foldAccents removes diacritics, by decomposing characters and dropping
+// their combining marks.
+func foldAccents(text string) string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), text)
+	if err != nil {
+		return text
+	}
+	return folded
 }
 
+// removeStopwords drops the words of text that are stopwords, compared
+// without case or surrounding punctuation.
 func (p *Processor) removeStopwords(text string) string {
 	words := strings.Fields(text)
 	var filtered []string
 
 	stopwords := getStopwords()
-	if len(p.config.CustomStopwords) > 0 {
-		stopwords = append(stopwords, p.config.CustomStopwords...)
+	for _, word := range p.config.CustomStopwords {
+		stopwords = append(stopwords, strings.ToLower(word))
 	}
 
 	for _, word := range words {
-		if !contains(stopwords, word) {
+		key := strings.ToLower(strings.TrimFunc(word, unicode.IsPunct))
+		if !contains(stopwords, key) {
 			filtered = append(filtered, word)
 		}
 	}
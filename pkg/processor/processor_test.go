@@ -1,9 +1,12 @@
 package processor_test
 
 import (
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xhad/yes/internal/models"
 	"github.com/xhad/yes/pkg/processor"
 )
@@ -11,14 +14,15 @@ import (
 func TestProcessor_Process(t *testing.T) {
 
 	config := processor.ProcessorConfig{
-		ChunkSize:          50,
-		ChunkOverlap:       10,
-		MinChunkLength:     20,
-		RemoveStopwords:    true,
-		CustomStopwords:    []string{"document"},
-		PreserveLineBreaks: false,
+		ChunkSize:       50,
+		ChunkOverlap:    10,
+		MinChunkLength:  20,
+		RemoveStopwords: true,
+		CustomStopwords: []string{"document"},
+		Lowercase:       true,
 	}
-	p := processor.NewWithConfig(config)
+	p, err := processor.New(config)
+	require.NoError(t, err)
 
 	documents := []models.Document{
 		{Content: "This is a test document. It contains several sentences to demonstrate text processing."},
@@ -31,6 +35,171 @@ func TestProcessor_Process(t *testing.T) {
 	assert.Contains(t, processedDocs[0].Chunks[0], "test document") // Checking if the chunk contains meaningful text after processing
 }
 
+// words counts whitespace-separated words as tokens.
+var words = processor.TokenizerFunc(func(text string) int {
+	return len(strings.Fields(text))
+})
+
+func TestProcessor_TokenChunks(t *testing.T) {
+	p, err := processor.New(processor.ProcessorConfig{
+		ChunkSize:      10,
+		ChunkOverlap:   4,
+		MinChunkLength: 1,
+		Tokenizer:      words,
+	})
+	require.NoError(t, err)
+
+	docs, err := p.Process([]models.Document{{
+		Content: "One two three. Four five six seven. Eight nine. Ten eleven twelve thirteen fourteen. Fifteen.",
+	}})
+	require.NoError(t, err)
+
+	// Each chunk repeats the whole sentences of the previous one that fit
+	// in four tokens
+	assert.Equal(t, []string{
+		"One two three. Four five six seven. Eight nine.",
+		"Eight nine. Ten eleven twelve thirteen fourteen. Fifteen.",
+	}, docs[0].Chunks)
+}
+
+func TestProcessor_LongSentences(t *testing.T) {
+	p, err := processor.New(processor.ProcessorConfig{
+		ChunkSize:      4,
+		ChunkOverlap:   1,
+		MinChunkLength: 1,
+		Tokenizer:      processor.TokenizerFunc(utf8.RuneCountInString),
+	})
+	require.NoError(t, err)
+
+	docs, err := p.Process([]models.Document{{Content: "日本語のドキュメント ab"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"日本語の", "ドキュメ", "ント", "ab"}, docs[0].Chunks)
+	for _, chunk := range docs[0].Chunks {
+		assert.True(t, utf8.ValidString(chunk), chunk)
+	}
+}
+
+func TestProcessor_ShortDocument(t *testing.T) {
+	p, err := processor.New(processor.ProcessorConfig{MinChunkLength: 50, Tokenizer: words})
+	require.NoError(t, err)
+
+	docs, err := p.Process([]models.Document{{Content: "Too short to fill a chunk."}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Too short to fill a chunk."}, docs[0].Chunks)
+}
+
+func TestProcessor_Validation(t *testing.T) {
+	_, err := processor.New(processor.ProcessorConfig{ChunkSize: 100, ChunkOverlap: 100, Tokenizer: words})
+	assert.EqualError(t, err, "chunk overlap of 100 tokens must be less than the chunk size of 100")
+
+	_, err = processor.New(processor.ProcessorConfig{ChunkSize: 1024, EmbeddingLimit: 512, Tokenizer: words})
+	assert.EqualError(t, err, "chunk size of 1024 tokens exceeds the 512 token input limit of the embedding model")
+
+	_, err = processor.New(processor.ProcessorConfig{ChunkSize: 512, EmbeddingLimit: 512, Tokenizer: words})
+	assert.NoError(t, err)
+
+	// NewWithConfig reports the invalid configuration once processing
+	p := processor.NewWithConfig(processor.ProcessorConfig{ChunkSize: 100, ChunkOverlap: 100, Tokenizer: words})
+	_, err = p.Process([]models.Document{{Content: "Some text."}})
+	assert.EqualError(t, err, "chunk overlap of 100 tokens must be less than the chunk size of 100")
+}
+
+func TestProcessor_KeepsLineBreaks(t *testing.T) {
+	chunks := chunk(t, processor.ProcessorConfig{ChunkSize: 100, ChunkOverlap: 10},
+		"Steps to  follow:\n- install   it\n  - with\tgo\n- run it  \n\n"+
+			"| Flag | Default |\n|------|---------|\n| -v   | false   |\n\n"+
+			"1. Build it.\n2. Ship it. Then rest.")
+
+	// Spaces collapse within lines, but lists and tables keep their lines,
+	// also between sentences
+	assert.Equal(t, []string{
+		"Steps to follow:\n- install it\n  - with go\n- run it\n\n" +
+			"| Flag | Default |\n|------|---------|\n| -v | false |\n\n" +
+			"1. Build it.\n2. Ship it. Then rest.",
+	}, chunks)
+}
+
+func TestProcessor_HeadingChunks(t *testing.T) {
+	p, err := processor.New(processor.ProcessorConfig{
+		ChunkSize:      14,
+		ChunkOverlap:   2,
+		MinChunkLength: 1,
+		Tokenizer:      words,
+	})
+	require.NoError(t, err)
+
+	docs, err := p.Process([]models.Document{{
+		Title: "Guide",
+		Markdown: "# Guide\n\nIntro to the guide.\n\n" +
+			"## Installation\n\nDownload it.\n\n```sh\n# not a heading\nmake install\n```\n\n" +
+			"## [Auth](/auth)\n\nSign in first. Then pick a [method](/auth/methods). Tokens suit scripts and services best.\n\n" +
+			"### Tokens ###\n\nCreate a token.\n\n" +
+			"Setext section\n--------------\n\nUnder a setext heading.\n",
+	}})
+	require.NoError(t, err)
+
+	doc := docs[0]
+	assert.Equal(t, []string{
+		"Guide",
+		"Guide > Installation",
+		"Guide > Auth",
+		"Guide > Auth",
+		"Guide > Auth > Tokens",
+		"Guide > Setext section",
+	}, doc.HeadingPaths)
+	assert.Equal(t, "Download it.\n\n```sh\n# not a heading\nmake install\n```", doc.Chunks[1])
+	assert.Equal(t, []string{"Sign in first. Then pick a method.", "Tokens suit scripts and services best."}, doc.Chunks[2:4])
+
+	// Chunks fit in a chunk once their heading path is prepended
+	for i, chunk := range doc.Chunks {
+		assert.LessOrEqual(t, words.Count(models.EmbeddingText(doc.HeadingPaths[i], chunk)), 14, chunk)
+	}
+}
+
+func TestProcessor_HeadingPathTitle(t *testing.T) {
+	p, err := processor.New(processor.ProcessorConfig{Tokenizer: words})
+	require.NoError(t, err)
+
+	docs, err := p.Process([]models.Document{
+		{Title: "Page", Markdown: "Intro.\n\n## Usage\n\nUse it."},
+		{Title: "Plain", Content: "No structure."},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"Intro.", "Use it."}, docs[0].Chunks)
+	assert.Equal(t, []string{"Page", "Page > Usage"}, docs[0].HeadingPaths)
+	assert.Equal(t, []string{"Plain"}, docs[1].HeadingPaths)
+}
+
+func TestProcessor_Normalize(t *testing.T) {
+	content := "The Café is NOT open. Call `fmt.Println()` instead!"
+
+	p, err := processor.New(processor.ProcessorConfig{Tokenizer: words})
+	require.NoError(t, err)
+	docs, err := p.Process([]models.Document{{Content: content}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{content}, docs[0].Chunks)
+	assert.Equal(t, []string{content}, docs[0].Normalized)
+
+	p, err = processor.New(processor.ProcessorConfig{
+		Tokenizer:        words,
+		Lowercase:        true,
+		FoldAccents:      true,
+		StripPunctuation: true,
+		RemoveStopwords:  true,
+		CustomStopwords:  []string{"Instead"},
+	})
+	require.NoError(t, err)
+	docs, err = p.Process([]models.Document{{Content: content}})
+	require.NoError(t, err)
+
+	// Chunks keep the original text for prompts
+	assert.Equal(t, []string{content}, docs[0].Chunks)
+	assert.Equal(t, []string{"cafe not open call fmt println"}, docs[0].Normalized)
+	assert.Equal(t, "where cafe", p.Normalize("Where is the café?"))
+}
+
 // func TestProcessor_CleanText(t *testing.T) {
 
 // 	config := processor.ProcessorConfig{
@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+)
+
+// headingSeparator joins the headings of a section's path.
+const headingSeparator = " > "
+
+// section is the text under a Markdown heading, up to the next heading.
+type section struct {
+	path []string // headings enclosing the text, outermost first
+	body string
+}
+
+var (
+	atxHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextHeading = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	codeFence     = regexp.MustCompile("^ {0,3}(```+|~~~+)")
+)
+
+// splitSections splits a Markdown document on its ATX (# Title) and setext
+// (Title over ===) headings. Each section records the path of headings it is
+// nested in; a heading closes every section at its level or deeper. Lines
+// inside fenced and <pre> code blocks are never headings. Text before the first
+// heading is a section with an empty path. Links and images are replaced by
+// their text, since their targets only add noise to embeddings.
+func splitSections(markdown string) []section {
+	var (
+		sections []section
+		headings []string
+		levels   []int
+		body     []string
+		fence    string
+		pre      bool
+	)
+	flush := func() {
+		text := strings.TrimSpace(strings.Join(body, "\n"))
+		if text != "" {
+			sections = append(sections, section{path: append([]string(nil), headings...), body: text})
+		}
+		body = body[:0]
+	}
+	open := func(level int, title string) {
+		flush()
+		for len(levels) > 0 && levels[len(levels)-1] >= level {
+			levels = levels[:len(levels)-1]
+			headings = headings[:len(headings)-1]
+		}
+		if title != "" {
+			levels = append(levels, level)
+			headings = append(headings, title)
+		}
+	}
+
+	for _, line := range strings.Split(markdown, "\n") {
+		if fence != "" {
+			if closesFence(line, fence) {
+				fence = ""
+			}
+			body = append(body, line)
+			continue
+		}
+		if pre {
+			pre = !preClose.MatchString(line)
+			body = append(body, line)
+			continue
+		}
+		if m := codeFence.FindStringSubmatch(line); m != nil {
+			fence = m[1]
+			body = append(body, line)
+			continue
+		}
+		if preOpen.MatchString(line) {
+			pre = !preClose.MatchString(line)
+			body = append(body, line)
+			continue
+		}
+
+		if m := atxHeading.FindStringSubmatch(line); m != nil {
+			open(len(m[1]), headingText(m[2]))
+			continue
+		}
+		// A setext underline turns the paragraph line above it into a heading
+		if m := setextHeading.FindStringSubmatch(line); m != nil && len(body) > 0 {
+			if title := headingText(body[len(body)-1]); title != "" {
+				body = body[:len(body)-1]
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				open(level, title)
+				continue
+			}
+		}
+		body = append(body, markdownLink.ReplaceAllString(line, "$1"))
+	}
+	flush()
+	return sections
+}
+
+// closesFence reports whether line closes the code block opened by fence.
+// As in CommonMark, the closing fence repeats the character of the opening
+// one at least as many times, indented up to three spaces, and is followed
+// by nothing but whitespace.
+func closesFence(line, fence string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	trimmed = strings.TrimRight(trimmed, " \t\r")
+	return len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// headingText returns the text of a heading with emphasis and link syntax
+// removed.
+func headingText(text string) string {
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = strings.NewReplacer("**", "", "__", "", "`", "").Replace(text)
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// markdownLink matches an inline link or image, capturing its text.
+var markdownLink = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
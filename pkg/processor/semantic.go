@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Embedder creates embeddings of texts, such as llm.Embedder's model.
+type Embedder interface {
+	CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// semanticChunker implements StrategySemantic.
+type semanticChunker struct {
+	tokenizer  Tokenizer
+	overlap    int
+	embedder   Embedder
+	percentile float64
+}
+
+// Chunk embeds every sentence of text and starts a new chunk where the
+// cosine distance between a sentence and the next is above the configured
+// percentile of the distances in text. Groups longer than size tokens are
+// split further like StrategySentence does.
+func (c semanticChunker) Chunk(text string, size int) ([]string, error) {
+	units := sentenceUnits(c.tokenizer, text, size)
+	if len(units) < 2 {
+		return texts(groupUnits(units, size, c.overlap, " ")), nil
+	}
+
+	embeddings, err := c.embedder.CreateEmbedding(context.Background(), texts(units))
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed sentences: %v", err)
+	}
+	if len(embeddings) != len(units) {
+		return nil, fmt.Errorf("got %d embeddings for %d sentences", len(embeddings), len(units))
+	}
+
+	distances := make([]float64, len(units)-1)
+	for i := range distances {
+		distances[i] = 1 - cosineSimilarity(embeddings[i], embeddings[i+1])
+	}
+	threshold := percentile(distances, c.percentile)
+
+	var chunks []string
+	start := 0
+	for i, distance := range distances {
+		if distance > threshold {
+			chunks = append(chunks, texts(groupUnits(units[start:i+1], size, c.overlap, " "))...)
+			start = i + 1
+		}
+	}
+	return append(chunks, texts(groupUnits(units[start:], size, c.overlap, " "))...), nil
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 if
+// either is zero.
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		if i >= len(b) {
+			break
+		}
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// percentile returns the p-th percentile of values, interpolating between
+// the closest ranks.
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
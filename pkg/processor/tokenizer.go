@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"log"
+	"regexp"
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// DefaultEncoding is the tiktoken encoding chunks are measured with when no
+// tokenizer is configured.
+const DefaultEncoding = "cl100k_base"
+
+// Tokenizer counts the tokens of a text the way a model does.
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// TokenizerFunc adapts an ordinary function to the Tokenizer interface.
+type TokenizerFunc func(text string) int
+
+// Count calls f(text).
+func (f TokenizerFunc) Count(text string) int {
+	return f(text)
+}
+
+// tiktokenTokenizer counts tokens with a tiktoken BPE encoding.
+type tiktokenTokenizer struct {
+	encoding *tiktoken.Tiktoken
+}
+
+// NewTiktoken returns a Tokenizer using the named tiktoken encoding, such
+// as cl100k_base. The encoding is downloaded on first use and cached in
+// TIKTOKEN_CACHE_DIR, or the system temporary directory.
+func NewTiktoken(encoding string) (Tokenizer, error) {
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	return tiktokenTokenizer{encoding: enc}, nil
+}
+
+func (t tiktokenTokenizer) Count(text string) int {
+	// Special tokens are counted as plain text, as embedding models see them
+	return len(t.encoding.Encode(text, nil, nil))
+}
+
+// tokenPattern matches the pieces estimateTokens counts: words, numbers and
+// single punctuation marks.
+var tokenPattern = regexp.MustCompile(`[\p{L}\p{M}]+|\p{N}+|[^\s\p{L}\p{M}\p{N}]`)
+
+// estimateTokens approximates a token count for when no encoding can be
+// loaded. Each word, number and punctuation mark counts as a token, plus a
+// token for every four bytes of a word beyond the first four, since BPE
+// splits long and rare words.
+func estimateTokens(text string) int {
+	n := 0
+	for _, piece := range tokenPattern.FindAllString(text, -1) {
+		n++
+		if len(piece) > 4 {
+			n += (len(piece) - 1) / 4
+		}
+	}
+	return n
+}
+
+// defaultTokenizer loads DefaultEncoding, falling back to estimateTokens
+// when it cannot be loaded, such as when offline without a cached copy. It
+// is loaded once and shared by every Processor.
+var defaultTokenizer = sync.OnceValue(func() Tokenizer {
+	tokenizer, err := NewTiktoken(DefaultEncoding)
+	if err != nil {
+		log.Printf("Error loading %s tokenizer, estimating token counts instead: %v", DefaultEncoding, err)
+		return TokenizerFunc(estimateTokens)
+	}
+	return tokenizer
+})
@@ -0,0 +1,218 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/xhad/yes/pkg/warc"
+)
+
+// redactedHeaders are never written to an archive in the clear, in addition
+// to the headers of the request's HTTP profile.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie"}
+
+// redactedResponseHeaders are the response headers never written to an
+// archive in the clear.
+var redactedResponseHeaders = []string{"Set-Cookie"}
+
+// transportFor returns the transport a client sends its requests through:
+// config.Transport in place of network when set, recording every exchange
+// to config.Archive when that is set. The values of redact are left out of
+// archived requests.
+func transportFor(config ScraperConfig, network http.RoundTripper, redact http.Header) http.RoundTripper {
+	transport := network
+	if config.Transport != nil {
+		transport = config.Transport
+	}
+	if config.Archive != nil {
+		transport = &archivingTransport{next: transport, archive: config.Archive, redact: redact, maxBodySize: config.MaxBodySize}
+	}
+	return transport
+}
+
+// archivingTransport writes every request sent through it and the response
+// it got to a WARC archive. Each redirect hop is a separate exchange, so
+// robots.txt files, sitemaps and redirects are archived as well as pages.
+type archivingTransport struct {
+	next        http.RoundTripper
+	archive     *warc.Writer
+	redact      http.Header
+	maxBodySize int64
+}
+
+func (t *archivingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &archivedBody{
+		ReadCloser: resp.Body,
+		transport:  t,
+		req:        req,
+		resp:       resp,
+		eof:        resp.ContentLength == 0,
+	}
+	return resp, nil
+}
+
+// archivedBody keeps a copy of a response body as it is read, and archives
+// the exchange once the body is closed. At most maxBodySize bytes are kept.
+// A longer body, or one closed before its end, is archived truncated, so
+// archiving never reads more of a response than the scraper does.
+type archivedBody struct {
+	io.ReadCloser
+	transport *archivingTransport
+	req       *http.Request
+	resp      *http.Response
+	buf       bytes.Buffer
+	eof       bool
+	overflow  bool
+	once      sync.Once
+}
+
+func (b *archivedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	keep := min(int64(n), b.transport.maxBodySize-int64(b.buf.Len()))
+	if keep > 0 {
+		b.buf.Write(p[:keep])
+	}
+	if keep < int64(n) {
+		b.overflow = true
+	}
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *archivedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		var truncated string
+		switch {
+		case b.overflow:
+			truncated = "length"
+		case !b.eof && int64(b.buf.Len()) != b.resp.ContentLength:
+			truncated = "unspecified"
+		}
+
+		// A page that cannot be archived is still crawled.
+		if err := b.transport.write(b.req, b.resp, b.buf.Bytes(), truncated); err != nil {
+			log.Printf("Error archiving %s: %v", b.req.URL, err)
+		}
+	})
+	return err
+}
+
+// write appends a response record and the request record it answers. The
+// response is marked with WARC-Truncated when truncated is not empty.
+func (t *archivingTransport) write(req *http.Request, resp *http.Response, body []byte, truncated string) error {
+	date := time.Now().UTC().Format(time.RFC3339)
+	responseID := warc.NewRecordID()
+
+	response := warc.Header{
+		{Name: "WARC-Type", Value: warc.TypeResponse},
+		{Name: "WARC-Record-ID", Value: responseID},
+		{Name: "WARC-Date", Value: date},
+		{Name: "WARC-Target-URI", Value: req.URL.String()},
+		{Name: "Content-Type", Value: warc.ContentTypeResponse},
+	}
+	if truncated != "" {
+		response.Set("WARC-Truncated", truncated)
+	}
+	err := t.archive.WriteRecord(&warc.Record{
+		Header:  response,
+		Content: archivedResponse(resp, body),
+	})
+	if err != nil {
+		return err
+	}
+
+	return t.archive.WriteRecord(&warc.Record{
+		Header: warc.Header{
+			{Name: "WARC-Type", Value: warc.TypeRequest},
+			{Name: "WARC-Date", Value: date},
+			{Name: "WARC-Target-URI", Value: req.URL.String()},
+			{Name: "WARC-Concurrent-To", Value: responseID},
+			{Name: "Content-Type", Value: warc.ContentTypeRequest},
+		},
+		Content: t.archivedRequest(req),
+	})
+}
+
+// archivedRequest returns req as sent on the wire, with credentials
+// replaced by "[redacted]".
+func (t *archivingTransport) archivedRequest(req *http.Request) []byte {
+	header := req.Header.Clone()
+	for _, name := range redactedHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, "[redacted]")
+		}
+	}
+	for name := range t.redact {
+		if header.Get(name) != "" {
+			header.Set(name, "[redacted]")
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), req.URL.Host)
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// archivedResponse returns resp as an HTTP/1.1 message, with cookies
+// replaced by "[redacted]". The body is the one the scraper read, after any
+// transfer or content encoding was removed, so its length replaces the
+// Content-Length the server sent.
+func archivedResponse(resp *http.Response, body []byte) []byte {
+	header := resp.Header.Clone()
+	header.Del("Transfer-Encoding")
+	header.Set("Content-Length", fmt.Sprint(len(body)))
+	for _, name := range redactedResponseHeaders {
+		if header.Get(name) != "" {
+			header.Set(name, "[redacted]")
+		}
+	}
+
+	status := resp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %s\r\n", status)
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// archiveCrawlStart writes a metadata record marking the start of a crawl
+// from startURL, which is where a replay begins.
+func (s *Scraper) archiveCrawlStart(startURL string) {
+	if s.config.Archive == nil {
+		return
+	}
+
+	fields := fmt.Sprintf("%s: %s\r\n%s: %d\r\n",
+		warc.FieldCrawlStart, time.Now().UTC().Format(time.RFC3339), warc.FieldMaxDepth, s.config.MaxDepth)
+	err := s.config.Archive.WriteRecord(&warc.Record{
+		Header: warc.Header{
+			{Name: "WARC-Type", Value: warc.TypeMetadata},
+			{Name: "WARC-Target-URI", Value: startURL},
+			{Name: "Content-Type", Value: warc.ContentTypeFields},
+		},
+		Content: []byte(fields),
+	})
+	if err != nil {
+		log.Printf("Error archiving crawl start: %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
+	"github.com/xhad/yes/pkg/warc"
+)
+
+// sortedDocuments returns docs sorted by URL without their crawl time,
+// which differs between runs.
+func sortedDocuments(docs []models.Document) []models.Document {
+	for _, doc := range docs {
+		delete(doc.Metadata, "time")
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].URL < docs[j].URL })
+	return docs
+}
+
+func TestArchiveReplay(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<html><head><title>Home</title></head><body><main><p>Home</p>
+			<a href="/old">old</a> <a href="/private">private</a> <a href="/missing">missing</a>
+		</main></body></html>`)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><head><title>New</title></head><body><main><p>New</p></main></body></html>`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var archive bytes.Buffer
+	config := ScraperConfig{
+		BaseURL:   server.URL,
+		RateLimit: 1000,
+		Retry:     RetryPolicy{MaxAttempts: 1},
+		HTTPProfiles: []HTTPProfile{{
+			Hosts:       []string{"127.0.0.1"},
+			Headers:     map[string]Secret{"X-Api-Key": {Value: "api-key-secret"}},
+			BearerToken: Secret{Value: "bearer-secret"},
+		}},
+	}
+
+	recording := config
+	recording.Archive = warc.NewWriter(&archive, true)
+	s, err := NewWithConfig(recording)
+	require.NoError(t, err)
+	recorded, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	require.Len(t, recorded, 2)
+
+	// Credentials never reach the archive.
+	records, err := warc.NewReader(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	var all []*warc.Record
+	for {
+		record, err := records.Next()
+		if err != nil {
+			break
+		}
+		assert.NotContains(t, string(record.Content), "secret")
+		all = append(all, record)
+	}
+	replay := warc.NewReplay(all)
+	assert.Equal(t, []string{server.URL + "/"}, replay.StartURLs())
+
+	var uris []string
+	for _, record := range all {
+		if record.Type() == warc.TypeResponse {
+			uris = append(uris, record.TargetURI())
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		server.URL + "/robots.txt", server.URL + "/", server.URL + "/old", server.URL + "/new", server.URL + "/missing",
+	}, uris)
+
+	// The replay runs without the server and yields the same documents.
+	server.Close()
+	replaying := config
+	replaying.Transport = replay
+	s, err = NewWithConfig(replaying)
+	require.NoError(t, err)
+	replayed, err := s.Scrape(config.BaseURL + "/")
+	require.NoError(t, err)
+
+	assert.Equal(t, sortedDocuments(recorded), sortedDocuments(replayed))
+
+	report := s.Report()
+	pages := make(map[string]models.CrawlPage)
+	for _, page := range report.Pages {
+		pages[page.URL] = page
+	}
+	assert.Equal(t, SkipDisallowed, pages[config.BaseURL+"/private"].Reason)
+	assert.Equal(t, http.StatusNotFound, pages[config.BaseURL+"/missing"].StatusCode)
+	assert.Equal(t, []string{config.BaseURL + "/new"}, pages[config.BaseURL+"/old"].Redirects)
+}
+
+func TestArchiveLimits(t *testing.T) {
+	large := strings.Repeat("x", 4096)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", http.NotFound)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "cookie-secret"})
+		fmt.Fprint(w, `<html><body><main><p>Home</p><a href="/large">large</a> <a href="/image.png">image</a></main></body></html>`)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body><main><p>"+large+"</p></main></body></html>")
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		fmt.Fprint(w, "\x89PNG\r\n\x1a\n"+large)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var archive bytes.Buffer
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:     server.URL,
+		RateLimit:   1000,
+		MaxBodySize: 2048,
+		Retry:       RetryPolicy{MaxAttempts: 1},
+		Archive:     warc.NewWriter(&archive, false),
+	})
+	require.NoError(t, err)
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+
+	records, err := warc.NewReader(bytes.NewReader(archive.Bytes()))
+	require.NoError(t, err)
+	responses := make(map[string]*warc.Record)
+	for {
+		record, err := records.Next()
+		if err != nil {
+			break
+		}
+		if record.Type() == warc.TypeResponse {
+			responses[record.TargetURI()] = record
+		}
+	}
+
+	// Cookies set by the site are redacted like the ones sent to it
+	home := responses[server.URL+"/"]
+	require.NotNil(t, home)
+	assert.NotContains(t, string(home.Content), "cookie-secret")
+	assert.Contains(t, string(home.Content), "Set-Cookie: [redacted]")
+	assert.Empty(t, home.Header.Get("WARC-Truncated"))
+
+	// Bodies are archived up to MaxBodySize, and only as far as they were
+	// read
+	assert.Equal(t, "length", responses[server.URL+"/large"].Header.Get("WARC-Truncated"))
+	assert.Less(t, len(responses[server.URL+"/large"].Content), 2048+512)
+	assert.Equal(t, "unspecified", responses[server.URL+"/image.png"].Header.Get("WARC-Truncated"))
+	for _, page := range s.Report().Pages {
+		if page.URL == server.URL+"/large" {
+			assert.Equal(t, "response larger than the 2048 byte limit", page.Reason)
+		}
+	}
+}
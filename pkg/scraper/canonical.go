@@ -0,0 +1,132 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// DefaultTrackingParams are removed from URLs when
+// ScraperConfig.TrackingParams is nil. A trailing "*" matches any suffix.
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"gclid", "dclid", "gbraid", "wbraid",
+	"fbclid", "msclkid", "yclid", "igshid", "twclid",
+	"mc_cid", "mc_eid",
+	"_ga", "_gl", "_hsenc", "_hsmi",
+	"ref_src",
+}
+
+// normalizeURL rewrites rawURL into the form the scraper fetches and stores:
+// the scheme and host are lowercased, default ports and the fragment are
+// dropped, tracking parameters are removed and the remaining query
+// parameters are sorted. The path is left alone since servers may treat
+// "/page" and "/page/" differently when resolving relative links.
+func (s *Scraper) normalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", err
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = canonicalHost(u)
+	u.Fragment = ""
+	u.RawFragment = ""
+
+	if u.RawQuery != "" {
+		query := u.Query()
+		for name := range query {
+			if isTrackingParam(name, s.trackingParams()) {
+				query.Del(name)
+			}
+		}
+		// Encode sorts by key, so equivalent queries compare equal.
+		u.RawQuery = query.Encode()
+	}
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// urlKey returns the key under which a URL is deduplicated. On top of
+// normalizeURL it treats "/page" and "/page/", and an empty path and "/", as
+// the same page.
+func (s *Scraper) urlKey(rawURL string) string {
+	normalized, err := s.normalizeURL(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	u, err := url.Parse(normalized)
+	if err != nil {
+		return normalized
+	}
+	u.Path = strings.TrimRight(u.Path, "/")
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawPath = ""
+	return u.String()
+}
+
+func (s *Scraper) trackingParams() []string {
+	if s.config.TrackingParams == nil {
+		return DefaultTrackingParams
+	}
+	return s.config.TrackingParams
+}
+
+// canonicalHost lowercases the host of u and drops the port when it is the
+// default for the scheme.
+func canonicalHost(u *url.URL) string {
+	host := strings.ToLower(u.Host)
+	switch {
+	case u.Scheme == "http" && strings.HasSuffix(host, ":80"):
+		return strings.TrimSuffix(host, ":80")
+	case u.Scheme == "https" && strings.HasSuffix(host, ":443"):
+		return strings.TrimSuffix(host, ":443")
+	}
+	return host
+}
+
+// isTrackingParam reports whether the query parameter name matches one of
+// patterns.
+func isTrackingParam(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// canonicalLink returns the absolute URL of the page's
+// <link rel="canonical">, or "" if it has none.
+func canonicalLink(doc *goquery.Document, base *url.URL) string {
+	var href string
+	doc.Find("link[rel]").EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		for _, rel := range strings.Fields(strings.ToLower(sel.AttrOr("rel", ""))) {
+			if rel == "canonical" {
+				href = strings.TrimSpace(sel.AttrOr("href", ""))
+				return false
+			}
+		}
+		return true
+	})
+	if href == "" {
+		return ""
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
@@ -0,0 +1,158 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	s := New("https://example.com")
+
+	tests := []struct {
+		in, normalized, key string
+	}{
+		{"https://example.com", "https://example.com", "https://example.com/"},
+		{"HTTPS://Example.COM:443/Docs/", "https://example.com/Docs/", "https://example.com/Docs"},
+		{"http://example.com:80/page#section", "http://example.com/page", "http://example.com/page"},
+		{"http://example.com:8080/page", "http://example.com:8080/page", "http://example.com:8080/page"},
+		{"https://example.com/page?utm_source=x&UTM_Medium=y&gclid=1", "https://example.com/page", "https://example.com/page"},
+		{"https://example.com/search?q=go&lang=en&fbclid=2", "https://example.com/search?lang=en&q=go", "https://example.com/search?lang=en&q=go"},
+		{"https://example.com/page?", "https://example.com/page", "https://example.com/page"},
+		{"https://example.com//", "https://example.com//", "https://example.com/"},
+	}
+
+	for _, tt := range tests {
+		normalized, err := s.normalizeURL(tt.in)
+		require.NoError(t, err)
+		assert.Equal(t, tt.normalized, normalized, tt.in)
+		assert.Equal(t, tt.key, s.urlKey(tt.in), tt.in)
+	}
+
+	_, err := s.normalizeURL("http://[::1")
+	assert.Error(t, err)
+}
+
+func TestNormalizeURLCustomTrackingParams(t *testing.T) {
+	s, err := NewWithConfig(ScraperConfig{BaseURL: "https://example.com", TrackingParams: []string{"session", "ref*"}})
+	require.NoError(t, err)
+
+	normalized, err := s.normalizeURL("https://example.com/?session=1&referrer=x&utm_source=y")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/?utm_source=y", normalized)
+}
+
+func TestCrawlCanonicalDeduplication(t *testing.T) {
+	mux := http.NewServeMux()
+	page := func(w http.ResponseWriter, body string) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head>%s</head><body><main><p>content</p></main></body></html>", body)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		page(w, `</head><body>
+			<a href="/page">a</a>
+			<a href="/page/">b</a>
+			<a href="/page#intro">c</a>
+			<a href="/page?utm_source=newsletter">d</a>
+			<a href="/old">e</a>
+			<a href="/print?id=1">f</a>
+			<a href="/guide/">g</a>
+			<head>`)
+	})
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) { page(w, "") })
+	mux.HandleFunc("/page/", func(w http.ResponseWriter, r *http.Request) { page(w, "") })
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) { page(w, "") })
+	mux.HandleFunc("/print", func(w http.ResponseWriter, r *http.Request) {
+		page(w, `<link rel="canonical" href="/article">`)
+	})
+	mux.HandleFunc("/guide/", func(w http.ResponseWriter, r *http.Request) {
+		// Relative links resolve against the trailing slash URL.
+		page(w, `</head><body><a href="intro">intro</a><head>`)
+	})
+	mux.HandleFunc("/guide/intro", func(w http.ResponseWriter, r *http.Request) { page(w, "") })
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var duplicates []string
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  2,
+		RateLimit: 1000,
+		OnSkip: func(url, reason string) {
+			if reason == SkipDuplicate {
+				duplicates = append(duplicates, url)
+			}
+		},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL)
+	require.NoError(t, err)
+
+	var urls []string
+	for _, doc := range docs {
+		urls = append(urls, doc.URL)
+		assert.Equal(t, documentID(doc.URL), doc.ID)
+	}
+	sort.Strings(urls)
+	assert.Equal(t, []string{
+		server.URL,
+		server.URL + "/article",
+		server.URL + "/guide/",
+		server.URL + "/guide/intro",
+		server.URL + "/new",
+		server.URL + "/page",
+	}, urls)
+	assert.Empty(t, duplicates)
+
+	for _, doc := range docs {
+		if doc.URL == server.URL+"/new" {
+			assert.Equal(t, server.URL+"/old", doc.Metadata["requestURL"])
+		}
+	}
+}
+
+func TestCrawlRedirectToSeenPage(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><main><a href="/target">t</a></main></body></html>`)
+	})
+	mux.HandleFunc("/target", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><main><a href="/alias">a</a></main></body></html>`)
+	})
+	mux.HandleFunc("/alias", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	var duplicates []string
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  3,
+		RateLimit: 1000,
+		OnSkip: func(url, reason string) {
+			if reason == SkipDuplicate {
+				duplicates = append(duplicates, url)
+			}
+		},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL)
+	require.NoError(t, err)
+	assert.Len(t, docs, 2)
+	assert.Equal(t, []string{server.URL + "/alias"}, duplicates)
+}
@@ -0,0 +1,311 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xhad/yes/internal/models"
+)
+
+// CheckpointStore saves the state of a crawl so that an interrupted crawl can
+// be resumed. Checkpoints are keyed by the start URL of the crawl.
+type CheckpointStore interface {
+	LoadCheckpoint(ctx context.Context, startURL string) (models.CrawlCheckpoint, bool, error)
+	SaveCheckpoint(ctx context.Context, checkpoint models.CrawlCheckpoint) error
+}
+
+// crawlRun is the state of the latest crawl, kept after the crawl returns so
+// that documents can still be confirmed with MarkStored.
+type crawlRun struct {
+	frontier *frontier
+	startURL string // key of the start URL
+	started  time.Time
+
+	mu       sync.Mutex
+	complete bool
+	finished time.Time
+}
+
+func (r *crawlRun) setComplete() {
+	r.mu.Lock()
+	r.complete = true
+	r.mu.Unlock()
+}
+
+func (r *crawlRun) setFinished() {
+	r.mu.Lock()
+	r.finished = time.Now()
+	r.mu.Unlock()
+}
+
+func (r *crawlRun) checkpoint() models.CrawlCheckpoint {
+	r.mu.Lock()
+	complete := r.complete
+	r.mu.Unlock()
+
+	return models.CrawlCheckpoint{
+		StartURL:  r.startURL,
+		Complete:  complete,
+		UpdatedAt: time.Now(),
+		Pages:     r.frontier.snapshot(),
+	}
+}
+
+func (s *Scraper) setRun(run *crawlRun) {
+	s.runMu.Lock()
+	s.run = run
+	s.runMu.Unlock()
+}
+
+func (s *Scraper) latestRun() *crawlRun {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	return s.run
+}
+
+// MarkStored confirms that the documents with the given URLs were stored.
+// Until then a checkpoint lists them as fetched, and resuming the crawl
+// fetches them again.
+func (s *Scraper) MarkStored(urls ...string) {
+	run := s.latestRun()
+	if run == nil {
+		return
+	}
+	for _, urlStr := range urls {
+		run.frontier.markStored(urlStr)
+	}
+}
+
+// SaveCheckpoint saves the state of the latest crawl to the checkpoint
+// store. The crawl saves itself periodically and when it returns; call
+// SaveCheckpoint again once its last documents have been stored and
+// confirmed.
+func (s *Scraper) SaveCheckpoint(ctx context.Context) error {
+	run := s.latestRun()
+	if s.config.Checkpoint == nil || run == nil {
+		return nil
+	}
+	if err := s.config.Checkpoint.SaveCheckpoint(ctx, run.checkpoint()); err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+	return nil
+}
+
+// autosave saves the latest crawl every CheckpointInterval until the returned
+// function is called.
+func (s *Scraper) autosave() (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(s.config.CheckpointInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.SaveCheckpoint(context.Background()); err != nil {
+					log.Printf("Error saving checkpoint: %v", err)
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// resume restores the frontier of run from its checkpoint when Resume is
+// set. It returns false if there is nothing to resume from.
+func (s *Scraper) resume(ctx context.Context, run *crawlRun) (bool, error) {
+	if !s.config.Resume || s.config.Checkpoint == nil {
+		return false, nil
+	}
+
+	checkpoint, ok, err := s.config.Checkpoint.LoadCheckpoint(ctx, run.startURL)
+	if err != nil {
+		return false, fmt.Errorf("failed to load checkpoint: %v", err)
+	}
+	if !ok {
+		return false, nil
+	}
+
+	done, queued := run.frontier.restore(checkpoint)
+	log.Printf("Resuming crawl of %s: %d pages done, %d queued", run.startURL, done, queued)
+	return true, nil
+}
+
+// page returns the checkpoint entry of item.
+func (item crawlItem) page() models.CrawlPage {
+	return models.CrawlPage{
+		URL:      item.url,
+		Referrer: item.referrer,
+		Depth:    item.depth,
+		Priority: item.priority,
+		Root:     item.root,
+	}
+}
+
+// record replaces the entry of page.URL with page.
+func (f *frontier) record(page models.CrawlPage) {
+	key := f.key(page.URL)
+	var finalKey string
+	if page.Status == models.PageFetched && page.FinalURL != "" {
+		finalKey = f.key(page.FinalURL)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.pages[key] = &page
+	if finalKey != "" {
+		f.final[finalKey] = &page
+	}
+}
+
+// markStored moves the fetched page whose URL or document URL is urlStr to
+// stored.
+func (f *frontier) markStored(urlStr string) {
+	key := f.key(urlStr)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	page, ok := f.pages[key]
+	if !ok {
+		page, ok = f.final[key]
+	}
+	if ok && page.Status == models.PageFetched {
+		page.Status = models.PageStored
+	}
+}
+
+// snapshot returns every page the frontier has seen, including the ones
+// still queued, ordered by depth and URL.
+func (f *frontier) snapshot() []models.CrawlPage {
+	f.mu.Lock()
+	pages := make([]models.CrawlPage, 0, len(f.pages)+f.size)
+	for _, page := range f.pages {
+		pages = append(pages, *page)
+	}
+	for _, items := range f.levels {
+		for _, item := range items {
+			page := item.page()
+			page.Status = models.PageQueued
+			pages = append(pages, page)
+		}
+	}
+	f.mu.Unlock()
+
+	sort.Slice(pages, func(i, j int) bool {
+		if pages[i].Depth != pages[j].Depth {
+			return pages[i].Depth < pages[j].Depth
+		}
+		return pages[i].URL < pages[j].URL
+	})
+	return pages
+}
+
+// restore loads a checkpoint into an empty frontier. Stored and skipped
+// pages are marked as seen; every other page is queued again, including
+// pages that failed or were fetched but never confirmed stored. It returns
+// the number of pages in each group.
+func (f *frontier) restore(checkpoint models.CrawlCheckpoint) (done, queued int) {
+	for _, page := range checkpoint.Pages {
+		switch page.Status {
+		case models.PageStored, models.PageSkipped:
+			page := page
+			f.mu.Lock()
+			f.seen[f.key(page.URL)] = true
+			f.pages[f.key(page.URL)] = &page
+			if page.FinalURL != "" {
+				f.seen[f.key(page.FinalURL)] = true
+				f.final[f.key(page.FinalURL)] = &page
+			}
+			f.mu.Unlock()
+			done++
+		default:
+			item := crawlItem{url: page.URL, depth: page.Depth, priority: page.Priority, root: page.Root, referrer: page.Referrer}
+			if f.pushItem(item) {
+				queued++
+			}
+		}
+	}
+	return done, queued
+}
+
+// FileCheckpointStore keeps checkpoints as JSON files in a directory, one
+// per start URL.
+type FileCheckpointStore struct {
+	Dir string
+}
+
+// NewFileCheckpointStore returns a store writing to dir, creating it if
+// needed.
+func NewFileCheckpointStore(dir string) (*FileCheckpointStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create checkpoint directory: %v", err)
+	}
+	return &FileCheckpointStore{Dir: dir}, nil
+}
+
+func (c *FileCheckpointStore) path(startURL string) string {
+	return filepath.Join(c.Dir, documentID(startURL)+".json")
+}
+
+// LoadCheckpoint implements CheckpointStore.
+func (c *FileCheckpointStore) LoadCheckpoint(ctx context.Context, startURL string) (models.CrawlCheckpoint, bool, error) {
+	data, err := os.ReadFile(c.path(startURL))
+	if os.IsNotExist(err) {
+		return models.CrawlCheckpoint{}, false, nil
+	}
+	if err != nil {
+		return models.CrawlCheckpoint{}, false, err
+	}
+
+	var checkpoint models.CrawlCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return models.CrawlCheckpoint{}, false, fmt.Errorf("invalid checkpoint %s: %v", c.path(startURL), err)
+	}
+	if checkpoint.StartURL != startURL {
+		return models.CrawlCheckpoint{}, false, nil
+	}
+	return checkpoint, true, nil
+}
+
+// SaveCheckpoint implements CheckpointStore. The file is replaced
+// atomically, so a crash while saving leaves the previous checkpoint intact.
+func (c *FileCheckpointStore) SaveCheckpoint(ctx context.Context, checkpoint models.CrawlCheckpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.Dir, ".checkpoint-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(checkpoint.StartURL))
+}
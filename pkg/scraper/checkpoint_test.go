@@ -0,0 +1,182 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
+)
+
+// newCountingSiteServer is newSiteServer that also counts the requests for
+// each path.
+func newCountingSiteServer(t *testing.T, pages map[string][]string) (*httptest.Server, func(path string) int) {
+	t.Helper()
+	var (
+		mu   sync.Mutex
+		hits = make(map[string]int)
+	)
+	site := newSiteServer(t, pages)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		site.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return hits[path]
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	server, hits := newCountingSiteServer(t, map[string][]string{
+		"/":  {"/a", "/b", "/c"},
+		"/a": {},
+		"/b": {},
+		"/c": {},
+	})
+	checkpoints, err := NewFileCheckpointStore(t.TempDir())
+	require.NoError(t, err)
+
+	config := ScraperConfig{
+		BaseURL:      server.URL,
+		RateLimit:    1000,
+		Workers:      1,
+		IgnoreRobots: true,
+		Checkpoint:   checkpoints,
+	}
+
+	// Store two pages, then interrupt the crawl.
+	s, err := NewWithConfig(config)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	docs, errc := s.ScrapeStream(ctx, server.URL+"/")
+	var stored []string
+	for doc := range docs {
+		if len(stored) < 2 {
+			stored = append(stored, doc.URL)
+			s.MarkStored(doc.URL)
+		}
+		if len(stored) == 2 {
+			cancel()
+		}
+	}
+	assert.ErrorIs(t, <-errc, context.Canceled)
+	require.NoError(t, s.SaveCheckpoint(context.Background()))
+
+	checkpoint, ok, err := checkpoints.LoadCheckpoint(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.False(t, checkpoint.Complete)
+
+	// Resume and expect the remaining pages without fetching the stored ones
+	// again.
+	config.Resume = true
+	s, err = NewWithConfig(config)
+	require.NoError(t, err)
+
+	resumed, err := s.Scrape(server.URL)
+	require.NoError(t, err)
+
+	urls := append([]string(nil), stored...)
+	for _, doc := range resumed {
+		urls = append(urls, doc.URL)
+		s.MarkStored(doc.URL)
+	}
+	sort.Strings(urls)
+	assert.Equal(t, []string{server.URL + "/", server.URL + "/a", server.URL + "/b", server.URL + "/c"}, urls)
+	assert.Equal(t, 1, hits("/"))
+	assert.Equal(t, 1, hits("/a"))
+	require.NoError(t, s.SaveCheckpoint(context.Background()))
+
+	checkpoint, ok, err = checkpoints.LoadCheckpoint(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, checkpoint.Complete)
+	for _, page := range checkpoint.Pages {
+		assert.Equal(t, models.PageStored, page.Status, page.URL)
+	}
+
+	// Resuming a finished crawl fetches nothing.
+	s, err = NewWithConfig(config)
+	require.NoError(t, err)
+	resumed, err = s.Scrape(server.URL)
+	require.NoError(t, err)
+	assert.Empty(t, resumed)
+	assert.Equal(t, 1, hits("/"))
+}
+
+func TestCheckpointRequeuesUnconfirmedPages(t *testing.T) {
+	f := newFrontier(100, nil)
+	checkpoint := models.CrawlCheckpoint{
+		StartURL: "https://example.com/",
+		Pages: []models.CrawlPage{
+			{URL: "https://example.com/", Root: true, Status: models.PageStored},
+			{URL: "https://example.com/old", FinalURL: "https://example.com/new", Depth: 1, Status: models.PageStored},
+			{URL: "https://example.com/robots", Depth: 1, Status: models.PageSkipped, Reason: SkipDisallowed},
+			{URL: "https://example.com/fetched", Depth: 1, Status: models.PageFetched},
+			{URL: "https://example.com/failed", Depth: 1, Status: models.PageFailed, Reason: "timeout"},
+			{URL: "https://example.com/queued", Depth: 2, Status: models.PageQueued},
+		},
+	}
+
+	done, queued := f.restore(checkpoint)
+	assert.Equal(t, 3, done)
+	assert.Equal(t, 3, queued)
+
+	assert.False(t, f.push("https://example.com/new", 1), "document URL of a stored page")
+	assert.False(t, f.push("https://example.com/robots", 1))
+	assert.Len(t, f.next(1), 2)
+	assert.Len(t, f.next(2), 1)
+
+	f.record(models.CrawlPage{URL: "https://example.com/fetched", FinalURL: "https://example.com/fetched-final", Depth: 1, Status: models.PageFetched})
+	f.markStored("https://example.com/fetched-final")
+
+	pages := make(map[string]models.CrawlPage)
+	for _, page := range f.snapshot() {
+		pages[page.URL] = page
+	}
+	assert.Len(t, pages, 6)
+	assert.Equal(t, models.PageStored, pages["https://example.com/fetched"].Status)
+	assert.Equal(t, models.PageQueued, pages["https://example.com/failed"].Status)
+	assert.Equal(t, SkipDisallowed, pages["https://example.com/robots"].Reason)
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	checkpoints, err := NewFileCheckpointStore(t.TempDir())
+	require.NoError(t, err)
+
+	_, ok, err := checkpoints.LoadCheckpoint(context.Background(), "https://example.com/")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for i := 1; i <= 2; i++ {
+		err = checkpoints.SaveCheckpoint(context.Background(), models.CrawlCheckpoint{
+			StartURL: "https://example.com/",
+			Pages:    []models.CrawlPage{{URL: fmt.Sprintf("https://example.com/%d", i), Status: models.PageQueued}},
+		})
+		require.NoError(t, err)
+	}
+
+	checkpoint, ok, err := checkpoints.LoadCheckpoint(context.Background(), "https://example.com/")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, checkpoint.Pages, 1)
+	assert.Equal(t, "https://example.com/2", checkpoint.Pages[0].URL)
+
+	_, ok, err = checkpoints.LoadCheckpoint(context.Background(), "https://example.org/")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,93 @@
+package scraper
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Kinds of content the scraper extracts documents from.
+const (
+	contentHTML = "html"
+	contentPDF  = "pdf"
+	contentText = "text"
+)
+
+// sniffLen is how much of a body is inspected to tell its type and charset,
+// the length the HTML spec prescans for a <meta charset>.
+const sniffLen = 1024
+
+// errUnsupportedContent is returned by fetch for responses no document can
+// be extracted from, such as images, archives or JSON.
+var errUnsupportedContent = errors.New("unsupported content type")
+
+// genericMediaTypes say nothing about the content, so the body is sniffed
+// instead.
+var genericMediaTypes = map[string]bool{
+	"":                         true,
+	"application/octet-stream": true,
+	"binary/octet-stream":      true,
+	"application/unknown":      true,
+}
+
+// contentKind decides how a response is extracted from its Content-Type
+// header, the first bytes of its body and its URL. It returns the media type
+// the decision was based on and "" as the kind when nothing can be
+// extracted.
+//
+// The declared type is trusted unless it is missing or generic, or the body
+// starts with the signature of a binary format, which catches servers that
+// label every file as HTML.
+func contentKind(contentType string, head []byte, u *url.URL) (kind, mediaType string) {
+	mediaType, _, _ = mime.ParseMediaType(contentType)
+	mediaType = strings.ToLower(mediaType)
+
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	sniffedBinary := sniffed != "application/octet-stream" && !strings.HasPrefix(sniffed, "text/")
+	switch {
+	case genericMediaTypes[mediaType] && isPDF(mediaType, u):
+		// A PDF file served without a specific type
+	case genericMediaTypes[mediaType] && len(head) > 0, sniffedBinary:
+		mediaType = sniffed
+	}
+
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return contentHTML, mediaType
+	case isPDF(mediaType, u):
+		return contentPDF, mediaType
+	case mediaType == "text/plain" || mediaType == "text/markdown" || mediaType == "text/x-markdown":
+		return contentText, mediaType
+	}
+	return "", mediaType
+}
+
+// decodeBody returns a reader converting body to UTF-8, along with the name
+// of its encoding. The charset is taken from the Content-Type header, a byte
+// order mark or a <meta> tag in head, in that order, and otherwise detected:
+// UTF-8 if head is valid UTF-8 and windows-1252 if not, as browsers do.
+func decodeBody(body io.Reader, head []byte, contentType string) (io.Reader, string) {
+	encoding, name, _ := charset.DetermineEncoding(head, contentType)
+	if name == "utf-8" {
+		// Invalid sequences are left for sanitizing before storage rather
+		// than replaced here, so valid pages pass through untouched.
+		return body, name
+	}
+	return encoding.NewDecoder().Reader(body), name
+}
+
+// textTitle is the title of a plain text document: its first line when that
+// is a Markdown heading, and otherwise the name of its file.
+func textTitle(text string, u *url.URL) string {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if title, ok := strings.CutPrefix(firstLine, "# "); ok {
+		return strings.TrimSpace(title)
+	}
+	return path.Base(u.Path)
+}
@@ -0,0 +1,154 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestContentKind(t *testing.T) {
+	page, _ := url.Parse("https://example.com/download")
+	pdfFile, _ := url.Parse("https://example.com/manual.pdf")
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	html := []byte("<!DOCTYPE html><html><body>hi</body></html>")
+
+	tests := []struct {
+		contentType string
+		head        []byte
+		u           *url.URL
+		kind        string
+		mediaType   string
+	}{
+		{"text/html; charset=utf-8", html, page, contentHTML, "text/html"},
+		{"application/xhtml+xml", html, page, contentHTML, "application/xhtml+xml"},
+		{"", html, page, contentHTML, "text/html"},
+		{"application/octet-stream", html, page, contentHTML, "text/html"},
+		{"text/plain", []byte("Just text."), page, contentText, "text/plain"},
+		{"text/markdown", []byte("# Title"), page, contentText, "text/markdown"},
+		{"application/pdf", []byte("%PDF-1.7"), page, contentPDF, "application/pdf"},
+		{"application/octet-stream", []byte("%PDF-1.7"), page, contentPDF, "application/pdf"},
+		{"", nil, pdfFile, contentPDF, ""},
+		{"image/png", png, page, "", "image/png"},
+		{"text/html", png, page, "", "image/png"},
+		{"", png, page, "", "image/png"},
+		{"application/json", []byte(`{"a": 1}`), page, "", "application/json"},
+		{"application/octet-stream", []byte{0, 1, 2, 3}, page, "", "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		kind, mediaType := contentKind(tt.contentType, tt.head, tt.u)
+		assert.Equal(t, tt.kind, kind, "%q %q", tt.contentType, tt.head)
+		assert.Equal(t, tt.mediaType, mediaType, "%q %q", tt.contentType, tt.head)
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	decode := func(body []byte, contentType string) (string, string) {
+		head := body
+		if len(head) > sniffLen {
+			head = head[:sniffLen]
+		}
+		reader, name := decodeBody(bytes.NewReader(body), head, contentType)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(data), name
+	}
+
+	sjis, err := japanese.ShiftJIS.NewEncoder().String("<p>日本語のドキュメント</p>")
+	require.NoError(t, err)
+	text, name := decode([]byte(sjis), "text/html; charset=Shift_JIS")
+	assert.Equal(t, "shift_jis", name)
+	assert.Equal(t, "<p>日本語のドキュメント</p>", text)
+
+	latin1 := []byte("<html><head><meta charset=\"iso-8859-1\"></head><body>Caf\xe9 cr\xe8me</body></html>")
+	text, name = decode(latin1, "text/html")
+	assert.Equal(t, "windows-1252", name)
+	assert.Contains(t, text, "Café crème")
+
+	text, name = decode([]byte("Caf\xe9 without a declared charset"), "text/plain")
+	assert.Equal(t, "windows-1252", name)
+	assert.Equal(t, "Café without a declared charset", text)
+
+	text, name = decode([]byte("Ünïcödé"), "")
+	assert.Equal(t, "utf-8", name)
+	assert.Equal(t, "Ünïcödé", text)
+}
+
+func TestScrapeContentTypes(t *testing.T) {
+	sjis, err := japanese.ShiftJIS.NewEncoder().String(`<html><head><title>ガイド</title></head><body><main><p>日本語のドキュメント</p></main></body></html>`)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><body><main><p>Home</p>
+				<a href="/ja">ja</a> <a href="/logo">logo</a> <a href="/api">api</a> <a href="/notes">notes</a>
+			</main></body></html>`)
+		case "/ja":
+			w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+			io.WriteString(w, sjis)
+		case "/logo":
+			// Served with the wrong type, as some servers do.
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"))
+		case "/api":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"html": "<p>not a page</p>"}`)
+		case "/notes":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			fmt.Fprint(w, "# Release notes\n\nVersion 2 adds PDF support.\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var skipped []string
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:      server.URL,
+		RateLimit:    1000,
+		Workers:      1,
+		IgnoreRobots: true,
+		OnSkip: func(url, reason string) {
+			skipped = append(skipped, strings.TrimPrefix(url, server.URL)+": "+reason)
+		},
+	})
+	require.NoError(t, err)
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+
+	byURL := make(map[string]models.Document)
+	for _, doc := range docs {
+		byURL[strings.TrimPrefix(doc.URL, server.URL)] = doc
+	}
+	require.Len(t, byURL, 3)
+
+	ja := byURL["/ja"]
+	assert.Equal(t, "ガイド", ja.Title)
+	assert.Equal(t, "日本語のドキュメント", ja.Content)
+	assert.Equal(t, "shift_jis", ja.Metadata["charset"])
+
+	notes := byURL["/notes"]
+	assert.Equal(t, "Release notes", notes.Title)
+	assert.Equal(t, "# Release notes\n\nVersion 2 adds PDF support.", notes.Markdown)
+	assert.Equal(t, 8, notes.Metadata["wordCount"])
+
+	assert.ElementsMatch(t, []string{"/logo: " + SkipUnsupported, "/api: " + SkipUnsupported}, skipped)
+
+	for _, page := range s.Report().Pages {
+		if page.URL == server.URL+"/logo" {
+			assert.Equal(t, models.PageSkipped, page.Status)
+			assert.Equal(t, "text/html", page.ContentType)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xhad/yes/internal/models"
+)
+
+// crawlItem is a URL waiting in the frontier.
+type crawlItem struct {
+	url      string
+	depth    int
+	priority int64  // higher is fetched first within a level
+	root     bool   // the start URL, whose failure aborts the crawl
+	referrer string // page the URL was found on
+}
+
+// frontier is the bounded, deduplicating queue of URLs still to be crawled.
+// Items are grouped by depth so that a whole level is drained before the next
+// one starts, which gives MaxDepth true breadth-first semantics: every page
+// is fetched at the shortest distance from the start URL.
+//
+// URLs are deduplicated on the key returned by key, so that spellings of the
+// same page are only fetched once. A nil key compares URLs verbatim.
+//
+// Every item taken from the frontier is recorded in pages together with its
+// status, which is what a checkpoint saves.
+type frontier struct {
+	mu      sync.Mutex
+	key     func(string) string
+	seen    map[string]bool
+	levels  map[int][]crawlItem
+	size    int
+	maxSize int
+	pages   map[string]*models.CrawlPage // by key
+	final   map[string]*models.CrawlPage // by key of the document URL
+}
+
+func newFrontier(maxSize int, key func(string) string) *frontier {
+	if key == nil {
+		key = func(urlStr string) string { return urlStr }
+	}
+	return &frontier{
+		key:     key,
+		seen:    make(map[string]bool),
+		levels:  make(map[int][]crawlItem),
+		maxSize: maxSize,
+		pages:   make(map[string]*models.CrawlPage),
+		final:   make(map[string]*models.CrawlPage),
+	}
+}
+
+// push queues urlStr at depth. It returns false if the URL was already seen
+// or the frontier is full.
+func (f *frontier) push(urlStr string, depth int) bool {
+	return f.pushItem(crawlItem{url: urlStr, depth: depth})
+}
+
+// pushItem queues item unless its URL was already seen or the frontier is
+// full.
+func (f *frontier) pushItem(item crawlItem) bool {
+	key := f.key(item.url)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.seen[key] || f.size >= f.maxSize {
+		return false
+	}
+
+	f.seen[key] = true
+	f.levels[item.depth] = append(f.levels[item.depth], item)
+	f.size++
+	return true
+}
+
+// claim marks urlStr as seen without queueing it, for pages reached under a
+// different URL through a redirect or a canonical link. It returns false if
+// the URL was already seen.
+func (f *frontier) claim(urlStr string) bool {
+	key := f.key(urlStr)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.seen[key] {
+		return false
+	}
+	f.seen[key] = true
+	return true
+}
+
+// next removes and returns every item queued at depth, highest priority
+// first. Items of equal priority keep their insertion order. They stay
+// recorded as queued until their outcome is known.
+func (f *frontier) next(depth int) []crawlItem {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	items := f.levels[depth]
+	delete(f.levels, depth)
+	f.size -= len(items)
+
+	for _, item := range items {
+		page := item.page()
+		page.Status = models.PageQueued
+		f.pages[f.key(item.url)] = &page
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].priority > items[j].priority
+	})
+	return items
+}
+
+// empty reports whether no URLs are waiting.
+func (f *frontier) empty() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.size == 0
+}
+
+// crawl walks the site breadth-first from startURL using a pool of
+// config.Workers goroutines and calls emit for every extracted document.
+// emit may be called concurrently. Only a failure to fetch the start URL or
+// the cancellation of ctx is returned; errors on child pages are logged and
+// the crawl continues.
+func (s *Scraper) crawl(ctx context.Context, startURL string, emit func(models.Document)) (err error) {
+	startURL, err = s.normalizeURL(startURL)
+	if err != nil {
+		return err
+	}
+	if !s.shouldProcessURL(startURL) {
+		return nil
+	}
+
+	f := newFrontier(s.config.MaxQueueSize, s.urlKey)
+	run := &crawlRun{frontier: f, startURL: s.urlKey(startURL), started: time.Now()}
+	s.setRun(run)
+	defer run.setFinished()
+	s.archiveCrawlStart(startURL)
+
+	resumed, err := s.resume(ctx, run)
+	if err != nil {
+		return err
+	}
+	if !resumed {
+		f.pushItem(crawlItem{url: startURL, priority: math.MaxInt64, root: true})
+
+		if s.config.UseSitemaps {
+			s.seedFromSitemaps(ctx, f, startURL)
+		}
+	}
+
+	if s.config.Checkpoint != nil {
+		stop := s.autosave()
+		defer func() {
+			stop()
+			if err == nil {
+				run.setComplete()
+			}
+			// ctx may be cancelled already, which must not prevent the
+			// final save.
+			if err := s.SaveCheckpoint(context.Background()); err != nil {
+				log.Printf("Error saving checkpoint: %v", err)
+			}
+		}()
+	}
+
+	// A resumed crawl may have nothing left at the lower depths, so run
+	// until the frontier is drained rather than until a level is empty.
+	for depth := 0; depth <= s.config.MaxDepth && !f.empty(); depth++ {
+		items := f.next(depth)
+		if len(items) == 0 {
+			continue
+		}
+
+		if err := s.crawlLevel(ctx, f, items, emit); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+// crawlLevel fetches every item of a single depth concurrently and queues the
+// links they contain one level deeper.
+func (s *Scraper) crawlLevel(ctx context.Context, f *frontier, items []crawlItem, emit func(models.Document)) error {
+	jobs := make(chan crawlItem, s.config.Workers)
+
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		rootErr error
+		emitMu  sync.Mutex
+	)
+
+	// Emit the documents of a page together, so the pages of a PDF file
+	// reach the consumer one after the other
+	emitPage := func(documents []models.Document) {
+		emitMu.Lock()
+		defer emitMu.Unlock()
+		for _, document := range documents {
+			emit(document)
+		}
+	}
+
+	for i := 0; i < s.config.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				if err := s.visit(ctx, f, item, emitPage); err != nil {
+					if item.root {
+						errOnce.Do(func() { rootErr = err })
+						continue
+					}
+					if ctx.Err() == nil {
+						log.Printf("Error scraping URL: %v", err)
+					}
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, item := range items {
+		select {
+		case jobs <- item:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return rootErr
+}
+
+// visit fetches a single frontier item, emits its documents and queues its
+// links.
+func (s *Scraper) visit(ctx context.Context, f *frontier, item crawlItem, emit func([]models.Document)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	page := item.page()
+
+	if !s.allowedByRobots(ctx, item.url) {
+		s.skip(f, page, SkipDisallowed)
+		return nil
+	}
+
+	if s.config.OnProgress != nil {
+		s.config.OnProgress(item.url)
+	}
+
+	cached := s.cachedPage(ctx, item.url)
+	documents, links, err := s.fetch(ctx, item.url, item.depth, cached, &page)
+	switch {
+	case errors.Is(err, errNotModified):
+		// The page is unchanged, but its subtree may not be, so keep
+		// crawling through the links recorded last time.
+		s.skip(f, page, SkipNotModified)
+		links = cached.Links
+	case errors.Is(err, errUnsupportedContent):
+		s.skip(f, page, SkipUnsupported)
+		return nil
+	case err != nil:
+		// Pages interrupted by cancellation stay queued and are fetched
+		// again on resume.
+		if ctx.Err() == nil {
+			page.Status, page.Reason = models.PageFailed, err.Error()
+			f.record(page)
+		}
+		return err
+	default:
+		// A page reached through a redirect or naming a canonical URL may
+		// already have been crawled under that URL. The documents of a
+		// page, one per page of a PDF file, share its URL.
+		docURL := documents[0].URL
+		if s.urlKey(docURL) != s.urlKey(item.url) && !f.claim(docURL) {
+			s.skip(f, page, SkipDuplicate)
+			return nil
+		}
+		// Record the page before emitting it, as the consumer may confirm
+		// it with MarkStored straight away.
+		page.Status = models.PageFetched
+		f.record(page)
+		emit(documents)
+	}
+
+	if item.depth >= s.config.MaxDepth {
+		return nil
+	}
+
+	for _, link := range links {
+		link, err := s.normalizeURL(link)
+		if err != nil {
+			continue
+		}
+		if s.shouldProcessURL(link) {
+			f.pushItem(crawlItem{url: link, depth: item.depth + 1, referrer: item.url})
+		}
+	}
+
+	return nil
+}
+
+// skip records a page that will not be fetched or emitted and reports it
+// through OnSkip.
+func (s *Scraper) skip(f *frontier, page models.CrawlPage, reason string) {
+	page.Status, page.Reason = models.PageSkipped, reason
+	f.record(page)
+	if s.config.OnSkip != nil {
+		s.config.OnSkip(page.URL, reason)
+	}
+}
+
+// cachedPage returns what the PageCache holds for urlStr, or nil if there is
+// no cache or no usable entry.
+func (s *Scraper) cachedPage(ctx context.Context, urlStr string) *models.CachedPage {
+	if s.config.PageCache == nil {
+		return nil
+	}
+
+	page, ok, err := s.config.PageCache.CachedPage(ctx, urlStr)
+	if err != nil {
+		log.Printf("Error reading page cache: %v", err)
+		return nil
+	}
+	if !ok || (page.ETag == "" && page.LastModified == "") {
+		return nil
+	}
+	return &page
+}
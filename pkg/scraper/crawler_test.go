@@ -0,0 +1,245 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSiteServer serves each page as an HTML document linking to the given
+// paths.
+func newSiteServer(t *testing.T, pages map[string][]string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		links, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><head><title>%s</title></head><body><main><p>Page %s</p>", r.URL.Path, r.URL.Path)
+		for _, link := range links {
+			fmt.Fprintf(w, `<a href="%s">link</a>`, link)
+		}
+		fmt.Fprint(w, "</main></body></html>")
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestFrontier(t *testing.T) {
+	f := newFrontier(2, nil)
+
+	assert.True(t, f.push("https://example.com/a", 0))
+	assert.False(t, f.push("https://example.com/a", 1), "duplicate URL")
+	assert.True(t, f.push("https://example.com/b", 1))
+	assert.False(t, f.push("https://example.com/c", 1), "frontier full")
+
+	assert.Len(t, f.next(0), 1)
+	assert.True(t, f.push("https://example.com/c", 1))
+
+	items := f.next(1)
+	require.Len(t, items, 2)
+	assert.Equal(t, "https://example.com/b", items[0].url)
+	assert.Empty(t, f.next(1))
+}
+
+func TestCrawlBreadthFirstDepth(t *testing.T) {
+	// /deep is reachable at depth 3 via /a -> /b -> /deep but also at depth 1
+	// directly from the root, so it must be recorded at depth 1.
+	server := newSiteServer(t, map[string][]string{
+		"/":     {"/a", "/deep"},
+		"/a":    {"/b"},
+		"/b":    {"/deep"},
+		"/deep": {"/leaf"},
+		"/leaf": {},
+	})
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  2,
+		RateLimit: 1000,
+		Workers:   3,
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+
+	depths := make(map[string]int)
+	for _, doc := range docs {
+		depths[doc.URL] = doc.Metadata["depth"].(int)
+	}
+
+	assert.Equal(t, map[string]int{
+		server.URL + "/":     0,
+		server.URL + "/a":    1,
+		server.URL + "/deep": 1,
+		server.URL + "/b":    2,
+		server.URL + "/leaf": 2,
+	}, depths)
+	assert.Equal(t, server.URL+"/", docs[0].URL)
+}
+
+func TestCrawlConcurrentProgress(t *testing.T) {
+	pages := map[string][]string{"/": nil}
+	for i := 0; i < 20; i++ {
+		path := fmt.Sprintf("/p%d", i)
+		pages["/"] = append(pages["/"], path)
+		pages[path] = []string{"/"}
+	}
+	server := newSiteServer(t, pages)
+
+	var progress int32
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  1,
+		RateLimit: 1000,
+		Workers:   8,
+		OnProgress: func(string) {
+			atomic.AddInt32(&progress, 1)
+		},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	assert.Len(t, docs, 21)
+	assert.Equal(t, int32(21), atomic.LoadInt32(&progress))
+}
+
+func TestCrawlRootError(t *testing.T) {
+	server := newSiteServer(t, map[string][]string{})
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, RateLimit: 1000})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/missing")
+	assert.Error(t, err)
+	assert.Empty(t, docs)
+}
+
+func TestScrapeContextCancel(t *testing.T) {
+	pages := map[string][]string{"/": nil}
+	for i := 0; i < 50; i++ {
+		path := fmt.Sprintf("/p%d", i)
+		pages["/"] = append(pages["/"], path)
+		pages[path] = nil
+	}
+	server := newSiteServer(t, pages)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var fetched int32
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  1,
+		RateLimit: 20,
+		Workers:   2,
+		OnProgress: func(string) {
+			if atomic.AddInt32(&fetched, 1) == 5 {
+				cancel()
+			}
+		},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.ScrapeContext(ctx, server.URL+"/")
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotEmpty(t, docs)
+	assert.Less(t, len(docs), 51)
+	assert.Equal(t, server.URL+"/", docs[0].URL)
+}
+
+func TestScrapeContextDeadline(t *testing.T) {
+	pages := map[string][]string{"/": nil}
+	for i := 0; i < 50; i++ {
+		path := fmt.Sprintf("/p%d", i)
+		pages["/"] = append(pages["/"], path)
+		pages[path] = nil
+	}
+	server := newSiteServer(t, pages)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, MaxDepth: 1, RateLimit: 10})
+	require.NoError(t, err)
+
+	start := time.Now()
+	docs, err := s.ScrapeContext(ctx, server.URL+"/")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotEmpty(t, docs)
+	assert.Less(t, time.Since(start), 2*time.Second)
+}
+
+func TestScrapeStream(t *testing.T) {
+	pages := map[string][]string{"/": nil}
+	for i := 0; i < 10; i++ {
+		path := fmt.Sprintf("/p%d", i)
+		pages["/"] = append(pages["/"], path)
+		pages[path] = nil
+	}
+	server := newSiteServer(t, pages)
+
+	var fetched int32
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  1,
+		RateLimit: 1000,
+		Workers:   1,
+		OnProgress: func(string) {
+			atomic.AddInt32(&fetched, 1)
+		},
+	})
+	require.NoError(t, err)
+
+	docs, errc := s.ScrapeStream(context.Background(), server.URL+"/")
+
+	first := <-docs
+	assert.Equal(t, server.URL+"/", first.URL)
+
+	// With nobody reading, the crawl stalls once the channel buffer and the
+	// single worker are occupied.
+	time.Sleep(100 * time.Millisecond)
+	assert.Less(t, atomic.LoadInt32(&fetched), int32(len(pages)))
+
+	count := 1
+	for range docs {
+		count++
+	}
+	assert.Equal(t, len(pages), count)
+	assert.NoError(t, <-errc)
+}
+
+func TestScrapeStreamCancel(t *testing.T) {
+	server := newSiteServer(t, map[string][]string{"/": {"/a", "/b"}, "/a": nil, "/b": nil})
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, MaxDepth: 1, RateLimit: 1000, Workers: 1})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	docs, errc := s.ScrapeStream(ctx, server.URL+"/")
+	<-docs
+	cancel()
+
+	for range docs {
+	}
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
+
+func TestInvalidWorkers(t *testing.T) {
+	_, err := NewWithConfig(ScraperConfig{BaseURL: "https://docs.example.com", Workers: -1})
+	assert.EqualError(t, err, "number of workers must be positive, got -1")
+
+	_, err = NewWithConfig(ScraperConfig{BaseURL: "https://docs.example.com", MaxQueueSize: -1})
+	assert.EqualError(t, err, "max queue size must be positive, got -1")
+}
@@ -0,0 +1,223 @@
+package scraper
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// ContentExtractor selects the main content of a page. Implementations may
+// modify doc, so links must be collected before Extract is called.
+type ContentExtractor interface {
+	Extract(doc *goquery.Document) *goquery.Selection
+}
+
+// ExtractorRule assigns a ContentExtractor to the pages of a set of hosts.
+// Hosts are matched exactly or, when written as "*.example.com", against
+// example.com and any of its subdomains.
+type ExtractorRule struct {
+	Hosts     []string
+	Extractor ContentExtractor
+}
+
+// Extractor types accepted by NewExtractor.
+const (
+	ExtractorSelectors   = "selectors"
+	ExtractorReadability = "readability"
+)
+
+// defaultContentSelectors are tried in order by the default extractor.
+var defaultContentSelectors = []string{
+	"main",
+	"article",
+	".content",
+	"#content",
+	".documentation",
+	"#documentation",
+}
+
+// NewExtractor builds an extractor from its configuration. An empty kind
+// selects the selector-based extractor, which uses the built-in selector list
+// when include is empty.
+func NewExtractor(kind string, include, exclude []string) (ContentExtractor, error) {
+	switch kind {
+	case "", ExtractorSelectors:
+		if len(include) == 0 {
+			include = defaultContentSelectors
+		}
+		return &SelectorExtractor{Include: include, Exclude: exclude}, nil
+	case ExtractorReadability:
+		return &ReadabilityExtractor{Exclude: exclude}, nil
+	default:
+		return nil, fmt.Errorf("unknown extractor type: %s", kind)
+	}
+}
+
+// SelectorExtractor returns the matches of the first Include selector found
+// on the page, with everything matching an Exclude selector removed. It falls
+// back to <body> when no Include selector matches.
+type SelectorExtractor struct {
+	Include []string
+	Exclude []string
+}
+
+func (e *SelectorExtractor) Extract(doc *goquery.Document) *goquery.Selection {
+	content := doc.Find("body")
+	for _, selector := range e.Include {
+		if selected := doc.Find(selector); selected.Length() > 0 {
+			content = selected
+			break
+		}
+	}
+
+	removeMatches(content, e.Exclude)
+	return content
+}
+
+// readabilityNoise is stripped before scoring since it never holds the main
+// content.
+var readabilityNoise = []string{
+	"script", "style", "noscript", "template", "iframe", "form",
+	"nav", "header", "footer", "aside",
+}
+
+var (
+	positiveClass = regexp.MustCompile(`(?i)article|body|content|entry|main|page|post|text|doc|markdown|prose`)
+	negativeClass = regexp.MustCompile(`(?i)nav|menu|footer|sidebar|comment|breadcrumb|banner|share|social|promo|related|cookie|toc|masthead`)
+)
+
+// ReadabilityExtractor finds the main content by scoring block elements on
+// text density, in the spirit of Mozilla's Readability: paragraphs award
+// points to their parent and grandparent based on length and commas, class
+// and id names nudge the score, and link-heavy blocks are penalized.
+type ReadabilityExtractor struct {
+	Exclude []string
+}
+
+func (e *ReadabilityExtractor) Extract(doc *goquery.Document) *goquery.Selection {
+	body := doc.Find("body")
+	removeMatches(body, readabilityNoise)
+	removeMatches(body, e.Exclude)
+
+	var (
+		candidates []*html.Node
+		scores     = make(map[*html.Node]float64)
+	)
+
+	addScore := func(node *html.Node, score float64) {
+		if node == nil || node.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[node]; !ok {
+			candidates = append(candidates, node)
+			scores[node] = classWeight(nodeSelection(node))
+		}
+		scores[node] += score
+	}
+
+	body.Find("p, pre, td, li, blockquote, dd").Each(func(_ int, sel *goquery.Selection) {
+		text := strings.TrimSpace(sel.Text())
+		if len(text) < 25 {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + math.Min(float64(len(text))/100, 3)
+		if parent := sel.Parent(); parent.Length() > 0 {
+			addScore(parent.Get(0), score)
+			if grandparent := parent.Parent(); grandparent.Length() > 0 {
+				addScore(grandparent.Get(0), score/2)
+			}
+		}
+	})
+
+	var (
+		best      *goquery.Selection
+		bestScore float64
+	)
+	for _, node := range candidates {
+		sel := nodeSelection(node)
+		score := scores[node] * (1 - linkDensity(sel))
+		if best == nil || score > bestScore {
+			best, bestScore = sel, score
+		}
+	}
+
+	if best == nil {
+		return body
+	}
+	return best
+}
+
+// nodeSelection wraps a single node of the page in a selection.
+func nodeSelection(node *html.Node) *goquery.Selection {
+	return goquery.NewDocumentFromNode(node).Selection
+}
+
+// classWeight scores an element on its class and id attributes.
+func classWeight(sel *goquery.Selection) float64 {
+	var weight float64
+	for _, attr := range []string{"class", "id"} {
+		value, ok := sel.Attr(attr)
+		if !ok {
+			continue
+		}
+		if negativeClass.MatchString(value) {
+			weight -= 25
+		}
+		if positiveClass.MatchString(value) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the fraction of sel's text that sits inside links.
+func linkDensity(sel *goquery.Selection) float64 {
+	textLength := len(strings.TrimSpace(sel.Text()))
+	if textLength == 0 {
+		return 0
+	}
+
+	var linkLength int
+	sel.Find("a").Each(func(_ int, a *goquery.Selection) {
+		linkLength += len(strings.TrimSpace(a.Text()))
+	})
+	return float64(linkLength) / float64(textLength)
+}
+
+// removeMatches deletes every descendant of sel matching one of selectors.
+func removeMatches(sel *goquery.Selection, selectors []string) {
+	if len(selectors) == 0 {
+		return
+	}
+	sel.Find(strings.Join(selectors, ", ")).Remove()
+}
+
+// matchHost reports whether host matches pattern, which is either an exact
+// host name or "*.example.com" to match example.com and all its subdomains.
+func matchHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	host = strings.ToLower(host)
+
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == suffix || strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// extractorFor returns the extractor of the first rule matching host, or the
+// default extractor.
+func (s *Scraper) extractorFor(host string) ContentExtractor {
+	for _, rule := range s.config.Extractors {
+		for _, pattern := range rule.Hosts {
+			if matchHost(pattern, host) {
+				return rule.Extractor
+			}
+		}
+	}
+	return s.config.DefaultExtractor
+}
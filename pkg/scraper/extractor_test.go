@@ -0,0 +1,117 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const clutteredPage = `
+<html><body>
+	<div id="top-nav" class="navbar">
+		<a href="/a">Home</a> <a href="/b">Guides</a> <a href="/c">API reference and more links</a>
+	</div>
+	<div class="layout">
+		<div class="sidebar">
+			<ul>
+				<li><a href="/x">Installing the command line tools on every platform</a></li>
+				<li><a href="/y">Configuring authentication for private repositories</a></li>
+			</ul>
+		</div>
+		<div class="docs-body">
+			<h1>Getting started</h1>
+			<p>The client library talks to the server over HTTP, retries failed requests, and caches responses.</p>
+			<p>Create a client with your API key, then call Connect before issuing any other requests.</p>
+			<p>Every method accepts a context, so callers can set deadlines and cancel work in flight.</p>
+			<span class="edit-link">Edit this page on GitHub</span>
+		</div>
+	</div>
+	<footer>Copyright, all rights reserved, terms and conditions apply to every page on this site.</footer>
+</body></html>`
+
+func parseHTML(t *testing.T, page string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	require.NoError(t, err)
+	return doc
+}
+
+func TestReadabilityExtractor(t *testing.T) {
+	content := (&ReadabilityExtractor{}).Extract(parseHTML(t, clutteredPage)).Text()
+
+	assert.Contains(t, content, "talks to the server over HTTP")
+	assert.Contains(t, content, "cancel work in flight")
+	assert.NotContains(t, content, "Installing the command line tools")
+	assert.NotContains(t, content, "API reference")
+	assert.NotContains(t, content, "Copyright")
+}
+
+func TestSelectorExtractor(t *testing.T) {
+	e := &SelectorExtractor{
+		Include: []string{"article", ".docs-body"},
+		Exclude: []string{".edit-link"},
+	}
+	content := e.Extract(parseHTML(t, clutteredPage)).Text()
+
+	assert.Contains(t, content, "Getting started")
+	assert.NotContains(t, content, "Edit this page")
+	assert.NotContains(t, content, "Installing the command line tools")
+
+	// Nothing matches, so the whole body is used.
+	content = (&SelectorExtractor{Include: []string{"article"}}).Extract(parseHTML(t, clutteredPage)).Text()
+	assert.Contains(t, content, "Copyright")
+}
+
+func TestNewExtractor(t *testing.T) {
+	e, err := NewExtractor("", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaultContentSelectors, e.(*SelectorExtractor).Include)
+
+	e, err = NewExtractor(ExtractorReadability, nil, []string{".ad"})
+	require.NoError(t, err)
+	assert.IsType(t, &ReadabilityExtractor{}, e)
+
+	_, err = NewExtractor("magic", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestMatchHost(t *testing.T) {
+	assert.True(t, matchHost("docs.example.com", "DOCS.example.com"))
+	assert.False(t, matchHost("docs.example.com", "api.example.com"))
+	assert.True(t, matchHost("*.example.com", "api.example.com"))
+	assert.True(t, matchHost("*.example.com", "example.com"))
+	assert.False(t, matchHost("*.example.com", "badexample.com"))
+}
+
+func TestScrapePicksExtractorByHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, clutteredPage)
+	}))
+	defer server.Close()
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  1,
+		RateLimit: 1000,
+		Extractors: []ExtractorRule{
+			{Hosts: []string{"127.0.0.1"}, Extractor: &ReadabilityExtractor{}},
+		},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	require.NotEmpty(t, docs)
+
+	// Links in the navigation are still followed even though the extractor
+	// drops them from the content.
+	assert.Len(t, docs, 6)
+	assert.Contains(t, docs[0].Content, "Getting started")
+	assert.NotContains(t, docs[0].Content, "Guides")
+}
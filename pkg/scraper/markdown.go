@@ -0,0 +1,378 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+var (
+	whitespaceRun = regexp.MustCompile(`\s+`)
+	languageClass = regexp.MustCompile(`(?:^|\s)(?:language|lang|highlight)-([A-Za-z0-9_+#.-]+)`)
+)
+
+// markdownConverter renders HTML as Markdown, keeping the structure that
+// plain text extraction loses: headings, lists, tables, links and fenced code
+// blocks with their language.
+type markdownConverter struct {
+	base *url.URL
+}
+
+// htmlToMarkdown converts every node of sel to Markdown. Relative links and
+// images are resolved against pageURL.
+func htmlToMarkdown(sel *goquery.Selection, pageURL string) string {
+	base, _ := url.Parse(pageURL)
+	c := &markdownConverter{base: base}
+
+	var out strings.Builder
+	for _, node := range sel.Nodes {
+		out.WriteString(c.block(node))
+		out.WriteString("\n\n")
+	}
+	return normalizeMarkdown(out.String())
+}
+
+// children renders the child nodes of n.
+func (c *markdownConverter) children(n *html.Node) string {
+	var out strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		text := c.block(child)
+		// Whitespace at the start of a line is not significant in HTML but
+		// would be in Markdown.
+		if s := out.String(); s == "" || strings.HasSuffix(s, "\n") {
+			text = strings.TrimLeft(text, " ")
+		}
+		out.WriteString(text)
+	}
+	return out.String()
+}
+
+// block renders a node and its subtree. Block level elements are surrounded
+// by blank lines which normalizeMarkdown later collapses.
+func (c *markdownConverter) block(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return whitespaceRun.ReplaceAllString(n.Data, " ")
+	case html.DocumentNode:
+		return c.children(n)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+
+	switch n.Data {
+	case "script", "style", "noscript", "template", "head":
+		return ""
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.Data[1] - '0')
+		text := inline(c.children(n))
+		if text == "" {
+			return ""
+		}
+		return "\n\n" + strings.Repeat("#", level) + " " + text + "\n\n"
+	case "p", "div", "section", "article", "main", "header", "footer", "aside", "nav", "figure", "dl":
+		return "\n\n" + strings.TrimSpace(c.children(n)) + "\n\n"
+	case "dt":
+		return "\n\n**" + inline(c.children(n)) + "**\n"
+	case "dd":
+		return "\n" + strings.TrimSpace(c.children(n)) + "\n\n"
+	case "br":
+		return "\n"
+	case "hr":
+		return "\n\n---\n\n"
+	case "strong", "b":
+		return wrapInline(c.children(n), "**")
+	case "em", "i":
+		return wrapInline(c.children(n), "*")
+	case "code", "kbd", "samp", "tt":
+		return inlineCode(nodeText(n))
+	case "a":
+		return c.link(n)
+	case "img":
+		return c.image(n)
+	case "pre":
+		return c.codeBlock(n)
+	case "ul", "ol":
+		return "\n\n" + c.list(n) + "\n\n"
+	case "blockquote":
+		inner := normalizeMarkdown(c.children(n))
+		return "\n\n" + prefixLines(inner, "> ", "> ") + "\n\n"
+	case "table":
+		return "\n\n" + c.table(n) + "\n\n"
+	}
+
+	return c.children(n)
+}
+
+func (c *markdownConverter) link(n *html.Node) string {
+	text := inline(c.children(n))
+	href := c.resolve(attr(n, "href"))
+	if text == "" {
+		return ""
+	}
+	if href == "" || strings.HasPrefix(href, "javascript:") {
+		return text
+	}
+	return fmt.Sprintf("[%s](%s)", text, href)
+}
+
+func (c *markdownConverter) image(n *html.Node) string {
+	src := c.resolve(attr(n, "src"))
+	if src == "" {
+		return ""
+	}
+	return fmt.Sprintf("![%s](%s)", attr(n, "alt"), src)
+}
+
+// resolve makes href absolute against the page URL.
+func (c *markdownConverter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || c.base == nil || strings.HasPrefix(href, "#") {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return c.base.ResolveReference(ref).String()
+}
+
+// codeBlock renders <pre> as a fenced code block, keeping its whitespace and
+// the language named by a language-*, lang-* or highlight-* class.
+func (c *markdownConverter) codeBlock(n *html.Node) string {
+	code := strings.Trim(nodeText(n), "\n")
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	return "\n\n" + fence + codeLanguage(n) + "\n" + code + "\n" + fence + "\n\n"
+}
+
+// codeLanguage looks for a language class on the <pre>, its <code> child or
+// its parent, which is where Sphinx puts it.
+func codeLanguage(pre *html.Node) string {
+	candidates := []*html.Node{pre, pre.Parent}
+	for child := pre.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.Data == "code" {
+			candidates = append([]*html.Node{child}, candidates...)
+		}
+	}
+
+	for _, node := range candidates {
+		if node == nil {
+			continue
+		}
+		if m := languageClass.FindStringSubmatch(attr(node, "class")); m != nil {
+			return strings.ToLower(m[1])
+		}
+	}
+	return ""
+}
+
+// list renders <ul> and <ol>. Nested content is indented to line up with the
+// text after the list marker.
+func (c *markdownConverter) list(n *html.Node) string {
+	var items []string
+	index := 1
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.Data != "li" {
+			continue
+		}
+
+		marker := "- "
+		if n.Data == "ol" {
+			marker = fmt.Sprintf("%d. ", index)
+			index++
+		}
+
+		content := normalizeMarkdown(c.children(child))
+		items = append(items, prefixLines(content, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a GitHub flavored Markdown table. The first row is used as
+// the header.
+func (c *markdownConverter) table(n *html.Node) string {
+	var rows [][]string
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.Data {
+			case "thead", "tbody", "tfoot":
+				walk(child)
+			case "tr":
+				var cells []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						text := inline(strings.ReplaceAll(c.children(cell), "\n", " "))
+						cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, cells)
+			}
+		}
+	}
+	walk(n)
+
+	columns := 0
+	for _, row := range rows {
+		if len(row) > columns {
+			columns = len(row)
+		}
+	}
+	if columns == 0 {
+		return ""
+	}
+
+	var out strings.Builder
+	writeRow := func(row []string) {
+		out.WriteString("|")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			out.WriteString(" " + cell + " |")
+		}
+		out.WriteString("\n")
+	}
+
+	writeRow(rows[0])
+	out.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(out.String(), "\n")
+}
+
+// attr returns the value of the named attribute of n.
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// nodeText returns the raw text of n with whitespace preserved.
+func nodeText(n *html.Node) string {
+	var out strings.Builder
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		if node.Type == html.TextNode {
+			out.WriteString(node.Data)
+		}
+		if node.Type == html.ElementNode && node.Data == "br" {
+			out.WriteString("\n")
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return out.String()
+}
+
+// inline collapses s onto a single line.
+func inline(s string) string {
+	return strings.TrimSpace(whitespaceRun.ReplaceAllString(s, " "))
+}
+
+// wrapInline surrounds the trimmed text with marker, keeping the surrounding
+// spaces outside the markers so the emphasis stays valid Markdown.
+func wrapInline(s, marker string) string {
+	text := strings.TrimSpace(s)
+	if text == "" {
+		return s
+	}
+	lead := s[:strings.Index(s, text)]
+	trail := s[len(lead)+len(text):]
+	return lead + marker + text + marker + trail
+}
+
+// inlineCode renders s as a code span, choosing a backtick run that does not
+// occur inside it.
+func inlineCode(s string) string {
+	s = whitespaceRun.ReplaceAllString(s, " ")
+	if strings.TrimSpace(s) == "" {
+		return s
+	}
+	ticks := "`"
+	for strings.Contains(s, ticks) {
+		ticks += "`"
+	}
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		s = " " + s + " "
+	}
+	return ticks + s + ticks
+}
+
+// prefixLines prefixes the first line of s with first and every other
+// non-empty line with rest.
+func prefixLines(s, first, rest string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + line
+		case line != "":
+			lines[i] = rest + line
+		case strings.TrimSpace(rest) != "":
+			lines[i] = strings.TrimSpace(rest)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// normalizeMarkdown trims trailing spaces and collapses runs of blank lines,
+// leaving the content of fenced code blocks untouched.
+func normalizeMarkdown(s string) string {
+	var (
+		out   []string
+		fence string
+		blank = true
+	)
+
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			out = append(out, line)
+			if trimmed == fence {
+				fence = ""
+			}
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "```") {
+			fence = strings.TrimRight(trimmed, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_+#.-")
+			out = append(out, strings.TrimRight(line, " \t"))
+			blank = false
+			continue
+		}
+
+		if trimmed == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+
+		out = append(out, strings.TrimRight(line, " \t"))
+		blank = false
+	}
+
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
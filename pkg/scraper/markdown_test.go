@@ -0,0 +1,75 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	page := `<html><body><main>
+		<h1>Client  guide</h1>
+		<p>Use the <code>Client</code> type to talk to the <a href="/api">API</a>.
+		It is <strong>safe</strong> for concurrent use.</p>
+		<h2>Install</h2>
+		<div class="highlight-shell"><pre>go get example.com/client</pre></div>
+		<pre><code class="language-go">func main() {
+	c := client.New()
+
+	c.Do(ctx)
+}</code></pre>
+		<ul>
+			<li>First item</li>
+			<li>Second item
+				<ol><li>Nested one</li><li>Nested two</li></ol>
+			</li>
+		</ul>
+		<table>
+			<thead><tr><th>Option</th><th>Default</th></tr></thead>
+			<tbody>
+				<tr><td><code>timeout</code></td><td>30s</td></tr>
+				<tr><td>mode</td><td>a | b</td></tr>
+			</tbody>
+		</table>
+		<blockquote><p>Note: keep your key secret.</p></blockquote>
+		<script>alert("x")</script>
+	</main></body></html>`
+
+	markdown := htmlToMarkdown(parseHTML(t, page).Find("main"), "https://example.com/docs/guide")
+
+	expected := "# Client guide\n\n" +
+		"Use the `Client` type to talk to the [API](https://example.com/api). It is **safe** for concurrent use.\n\n" +
+		"## Install\n\n" +
+		"```shell\ngo get example.com/client\n```\n\n" +
+		"```go\nfunc main() {\n\tc := client.New()\n\n\tc.Do(ctx)\n}\n```\n\n" +
+		"- First item\n" +
+		"- Second item\n\n" +
+		"  1. Nested one\n" +
+		"  2. Nested two\n\n" +
+		"| Option | Default |\n" +
+		"| --- | --- |\n" +
+		"| `timeout` | 30s |\n" +
+		"| mode | a \\| b |\n\n" +
+		"> Note: keep your key secret."
+
+	assert.Equal(t, expected, markdown)
+}
+
+func TestCodeFenceAvoidsBackticks(t *testing.T) {
+	markdown := htmlToMarkdown(parseHTML(t, "<pre>```\nnested\n```</pre>").Find("pre"), "")
+	assert.Equal(t, "````\n```\nnested\n```\n````", markdown)
+}
+
+func TestScrapeStoresMarkdown(t *testing.T) {
+	server := newSiteServer(t, map[string][]string{"/": nil})
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, RateLimit: 1000})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "Page /", docs[0].Content)
+	assert.Equal(t, "Page /", docs[0].Markdown)
+}
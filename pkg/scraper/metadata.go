@@ -0,0 +1,290 @@
+package scraper
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// Heading is an entry of a page's heading outline.
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+}
+
+// pageMetadata collects what a page says about itself: its description,
+// OpenGraph and Twitter card fields, language, breadcrumbs, canonical URL,
+// author and publication dates. Fields the page does not provide are left
+// out. It reads the whole page, so it must run before content extraction
+// removes navigation.
+func pageMetadata(doc *goquery.Document, base *url.URL) map[string]interface{} {
+	metadata := make(map[string]interface{})
+	set := func(key, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			metadata[key] = value
+		}
+	}
+
+	openGraph := metaProperties(doc, "og:")
+	twitter := metaProperties(doc, "twitter:")
+	if len(openGraph) > 0 {
+		metadata["openGraph"] = openGraph
+	}
+	if len(twitter) > 0 {
+		metadata["twitter"] = twitter
+	}
+
+	set("description", firstNonEmpty(metaContent(doc, "description"), openGraph["description"], twitter["description"]))
+	set("lang", doc.Find("html").AttrOr("lang", ""))
+	set("canonical", canonicalLink(doc, base))
+
+	ld := parseJSONLD(doc)
+	set("author", firstNonEmpty(ld.author, metaContent(doc, "author"), metaContent(doc, "article:author")))
+	set("published", firstNonEmpty(ld.published, metaContent(doc, "article:published_time")))
+	set("modified", firstNonEmpty(ld.modified, metaContent(doc, "article:modified_time"), openGraph["updated_time"]))
+
+	breadcrumbs := ld.breadcrumbs
+	if len(breadcrumbs) == 0 {
+		breadcrumbs = breadcrumbTrail(doc)
+	}
+	if len(breadcrumbs) > 0 {
+		metadata["breadcrumbs"] = breadcrumbs
+	}
+
+	return metadata
+}
+
+// metaContent returns the content of the first <meta> whose name or property
+// is key, compared case-insensitively.
+func metaContent(doc *goquery.Document, key string) string {
+	var content string
+	doc.Find("meta[content]").EachWithBreak(func(_ int, sel *goquery.Selection) bool {
+		name := sel.AttrOr("name", sel.AttrOr("property", ""))
+		if strings.EqualFold(name, key) {
+			content = strings.TrimSpace(sel.AttrOr("content", ""))
+			return content == ""
+		}
+		return true
+	})
+	return content
+}
+
+// metaProperties returns the <meta> fields whose name or property starts
+// with prefix, keyed by the rest of the name. Sites use both attributes for
+// OpenGraph and Twitter fields. The first value of a repeated field wins.
+func metaProperties(doc *goquery.Document, prefix string) map[string]string {
+	properties := make(map[string]string)
+	doc.Find("meta[content]").Each(func(_ int, sel *goquery.Selection) {
+		name := strings.ToLower(sel.AttrOr("property", sel.AttrOr("name", "")))
+		key, ok := strings.CutPrefix(name, prefix)
+		content := strings.TrimSpace(sel.AttrOr("content", ""))
+		if !ok || key == "" || content == "" {
+			return
+		}
+		if _, seen := properties[key]; !seen {
+			properties[key] = content
+		}
+	})
+	return properties
+}
+
+// breadcrumbSelectors find breadcrumb navigation on pages without JSON-LD
+// breadcrumbs.
+var breadcrumbSelectors = []string{
+	`nav[aria-label="breadcrumb" i]`,
+	`nav[aria-label="breadcrumbs" i]`,
+	`[itemtype$="BreadcrumbList"]`,
+	`.breadcrumb`,
+	`.breadcrumbs`,
+}
+
+// breadcrumbTrail returns the entries of the page's breadcrumb navigation.
+func breadcrumbTrail(doc *goquery.Document) []string {
+	for _, selector := range breadcrumbSelectors {
+		nav := doc.Find(selector).First()
+		if nav.Length() == 0 {
+			continue
+		}
+
+		items := nav.Find("li")
+		if items.Length() == 0 {
+			items = nav.Find("a")
+		}
+		var trail []string
+		items.Each(func(_ int, sel *goquery.Selection) {
+			if text := strings.Join(strings.Fields(sel.Text()), " "); text != "" {
+				trail = append(trail, text)
+			}
+		})
+		if len(trail) > 0 {
+			return trail
+		}
+	}
+	return nil
+}
+
+// headingOutline returns the headings of the extracted content in document
+// order.
+func headingOutline(content *goquery.Selection) []Heading {
+	var outline []Heading
+	content.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, sel *goquery.Selection) {
+		text := strings.Join(strings.Fields(sel.Text()), " ")
+		if text == "" {
+			return
+		}
+		level := int(goquery.NodeName(sel)[1] - '0')
+		outline = append(outline, Heading{Level: level, Text: text})
+	})
+	return outline
+}
+
+// wordCount counts the words of the text in content. Text nodes are counted
+// separately, so adjacent block elements do not run together as they do in
+// Selection.Text.
+func wordCount(content *goquery.Selection) int {
+	var (
+		n    int
+		walk func(*html.Node)
+	)
+	walk = func(node *html.Node) {
+		switch {
+		case node.Type == html.TextNode:
+			n += len(strings.Fields(node.Data))
+		case node.Type == html.ElementNode && (node.Data == "script" || node.Data == "style"):
+			return
+		}
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	for _, node := range content.Nodes {
+		walk(node)
+	}
+	return n
+}
+
+// jsonLD holds the fields read from a page's JSON-LD structured data.
+type jsonLD struct {
+	author      string
+	published   string
+	modified    string
+	breadcrumbs []string
+}
+
+// parseJSONLD reads every <script type="application/ld+json"> block of the
+// page. Blocks may hold a single object, an array or an @graph; the first
+// object providing a field wins. Invalid blocks are ignored.
+func parseJSONLD(doc *goquery.Document) jsonLD {
+	var ld jsonLD
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, sel *goquery.Selection) {
+		var data interface{}
+		if err := json.Unmarshal([]byte(sel.Text()), &data); err != nil {
+			return
+		}
+		ld.collect(data)
+	})
+	return ld
+}
+
+func (ld *jsonLD) collect(data interface{}) {
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			ld.collect(item)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			ld.collect(graph)
+		}
+
+		if hasType(v, "BreadcrumbList") {
+			if len(ld.breadcrumbs) == 0 {
+				ld.breadcrumbs = breadcrumbList(v)
+			}
+			return
+		}
+
+		if ld.author == "" {
+			ld.author = personName(v["author"])
+		}
+		if ld.published == "" {
+			ld.published, _ = v["datePublished"].(string)
+		}
+		if ld.modified == "" {
+			ld.modified, _ = v["dateModified"].(string)
+		}
+	}
+}
+
+// hasType reports whether the JSON-LD object has the given @type, which may
+// be a string or an array of strings.
+func hasType(object map[string]interface{}, typ string) bool {
+	switch t := object["@type"].(type) {
+	case string:
+		return t == typ
+	case []interface{}:
+		for _, item := range t {
+			if item == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// personName returns the name of a JSON-LD author, which may be a string, a
+// Person or Organization object, or an array of them joined by commas.
+func personName(data interface{}) string {
+	switch v := data.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case map[string]interface{}:
+		name, _ := v["name"].(string)
+		return strings.TrimSpace(name)
+	case []interface{}:
+		var names []string
+		for _, item := range v {
+			if name := personName(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// breadcrumbList returns the names of a BreadcrumbList's items in the order
+// they are listed.
+func breadcrumbList(list map[string]interface{}) []string {
+	items, _ := list["itemListElement"].([]interface{})
+	var trail []string
+	for _, item := range items {
+		element, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := element["name"].(string)
+		if name == "" {
+			// The name may be on the nested item instead
+			if nested, ok := element["item"].(map[string]interface{}); ok {
+				name, _ = nested["name"].(string)
+			}
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			trail = append(trail, name)
+		}
+	}
+	return trail
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,89 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPageMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html lang="en-GB"><head>
+			<title>Install</title>
+			<meta name="description" content="How to install the tool.">
+			<meta property="og:title" content="Installing">
+			<meta property="og:image" content="https://example.com/card.png">
+			<meta name="twitter:card" content="summary">
+			<link rel="canonical" href="/docs/install">
+			<script type="application/ld+json">{
+				"@context": "https://schema.org",
+				"@graph": [
+					{"@type": "TechArticle", "author": [{"@type": "Person", "name": "Ada"}, {"name": "Grace"}],
+					 "datePublished": "2024-01-02", "dateModified": "2024-03-04T10:00:00Z"},
+					{"@type": "BreadcrumbList", "itemListElement": [
+						{"@type": "ListItem", "position": 1, "name": "Docs"},
+						{"@type": "ListItem", "position": 2, "item": {"@id": "/docs/guides", "name": "Guides"}}
+					]}
+				]
+			}</script>
+		</head><body>
+			<nav aria-label="Breadcrumb"><ol><li>Ignored</li></ol></nav>
+			<main><h1>Install</h1><p>Download the binary and run it.</p>
+			<h2>Linux</h2><p>Use the package.</p><h3> From source </h3></main>
+			<footer><a href="/docs/next">Next</a></footer>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, RateLimit: 1000, IgnoreRobots: true})
+	require.NoError(t, err)
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+
+	metadata := docs[0].Metadata
+	assert.Equal(t, "How to install the tool.", metadata["description"])
+	assert.Equal(t, "en-GB", metadata["lang"])
+	assert.Equal(t, server.URL+"/docs/install", metadata["canonical"])
+	assert.Equal(t, map[string]string{"title": "Installing", "image": "https://example.com/card.png"}, metadata["openGraph"])
+	assert.Equal(t, map[string]string{"card": "summary"}, metadata["twitter"])
+	assert.Equal(t, "Ada, Grace", metadata["author"])
+	assert.Equal(t, "2024-01-02", metadata["published"])
+	assert.Equal(t, "2024-03-04T10:00:00Z", metadata["modified"])
+	assert.Equal(t, []string{"Docs", "Guides"}, metadata["breadcrumbs"])
+	assert.Equal(t, []Heading{{1, "Install"}, {2, "Linux"}, {3, "From source"}}, metadata["headings"])
+	assert.Equal(t, 13, metadata["wordCount"])
+
+	// Links are kept out of the metadata every chunk is stored with
+	assert.Equal(t, []string{server.URL + "/docs/next"}, docs[0].Links)
+	assert.NotContains(t, metadata, "links")
+}
+
+func TestPageMetadataFallbacks(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(`<html><head>
+		<meta property="og:description" content="From OpenGraph">
+		<meta name="author" content="Docs Team">
+		<meta property="article:published_time" content="2023-05-06">
+		<script type="application/ld+json">not json</script>
+	</head><body>
+		<nav aria-label="breadcrumb"><ol><li><a href="/">Home</a></li><li>API</li></ol></nav>
+	</body></html>`))
+	require.NoError(t, err)
+
+	base, _ := url.Parse("https://example.com/api")
+	metadata := pageMetadata(doc, base)
+	assert.Equal(t, "From OpenGraph", metadata["description"])
+	assert.Equal(t, "Docs Team", metadata["author"])
+	assert.Equal(t, "2023-05-06", metadata["published"])
+	assert.Equal(t, []string{"Home", "API"}, metadata["breadcrumbs"])
+	assert.NotContains(t, metadata, "lang")
+	assert.NotContains(t, metadata, "canonical")
+	assert.NotContains(t, metadata, "modified")
+}
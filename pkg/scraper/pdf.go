@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+	"github.com/xhad/yes/internal/models"
+)
+
+// isPDF reports whether a response is a PDF file, by its content type or,
+// for servers that send a generic type, by the extension of its URL.
+func isPDF(contentType string, u *url.URL) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/pdf", "application/x-pdf":
+		return true
+	case "", "application/octet-stream", "binary/octet-stream":
+		return strings.HasSuffix(strings.ToLower(u.Path), ".pdf")
+	}
+	return false
+}
+
+// pdfFile is the text and document information of a PDF file.
+type pdfFile struct {
+	title   string
+	author  string
+	created string
+	pages   []string // text of each page, in order
+}
+
+// readPDF extracts the text of every page of a PDF file. The parser panics
+// on some malformed files, which is turned into an error.
+func readPDF(data []byte) (file pdfFile, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("invalid PDF: %v", r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return pdfFile{}, fmt.Errorf("invalid PDF: %v", err)
+	}
+
+	info := reader.Trailer().Key("Info")
+	file.title = strings.TrimSpace(info.Key("Title").Text())
+	file.author = strings.TrimSpace(info.Key("Author").Text())
+	file.created = pdfDate(info.Key("CreationDate").Text())
+
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			file.pages = append(file.pages, "")
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return pdfFile{}, fmt.Errorf("failed to read page %d: %v", i, err)
+		}
+		file.pages = append(file.pages, text)
+	}
+	return file, nil
+}
+
+// pdfDate converts a PDF date such as "D:20240102030405+01'00'" to RFC 3339.
+// Dates that do not parse are returned unchanged.
+func pdfDate(value string) string {
+	value = strings.TrimSpace(value)
+	raw := strings.TrimPrefix(value, "D:")
+	raw = strings.ReplaceAll(raw, "'", "")
+
+	for _, layout := range []string{"20060102150405Z0700", "20060102150405Z07", "20060102150405", "20060102"} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.Format(time.RFC3339)
+		}
+	}
+	return value
+}
+
+// fetchPDF reads the PDF file in body and returns a document for every page
+// with text. The documents share the URL of the file and carry their page
+// number, so citations can point at a single page.
+func (s *Scraper) fetchPDF(body io.Reader, docURL string, metadata map[string]interface{}) ([]models.Document, error) {
+	// The file is read into memory to be parsed, up to MaxBodySize
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.config.MaxBodySize {
+		return nil, s.errBodyTooLarge()
+	}
+
+	file, err := readPDF(data)
+	if err != nil {
+		return nil, err
+	}
+
+	title := file.title
+	if title == "" {
+		if u, err := url.Parse(docURL); err == nil {
+			title = path.Base(u.Path)
+		}
+	}
+
+	var documents []models.Document
+	for i, text := range file.pages {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		pageMetadata := make(map[string]interface{}, len(metadata)+5)
+		for key, value := range metadata {
+			pageMetadata[key] = value
+		}
+		pageMetadata["page"] = i + 1
+		pageMetadata["pageCount"] = len(file.pages)
+		pageMetadata["wordCount"] = len(strings.Fields(text))
+		if file.author != "" {
+			pageMetadata["author"] = file.author
+		}
+		if file.created != "" {
+			pageMetadata["published"] = file.created
+		}
+
+		documents = append(documents, models.Document{
+			ID:       documentID(fmt.Sprintf("%s#page=%d", docURL, i+1)),
+			URL:      docURL,
+			Title:    title,
+			Content:  s.cleanContent(text),
+			Markdown: text,
+			Metadata: pageMetadata,
+		})
+	}
+
+	if len(documents) == 0 {
+		return nil, errors.New("no text in PDF")
+	}
+	return documents, nil
+}
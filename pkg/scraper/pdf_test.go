@@ -0,0 +1,131 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF returns a PDF file with the given document information and one
+// page per element of pages, each showing its lines of text.
+func buildPDF(title, author string, pages ...[]string) []byte {
+	kids := ""
+	for i := range pages {
+		kids += fmt.Sprintf("%d 0 R ", 5+2*i)
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Author (%s) /CreationDate (D:20240102030405Z) >>", title, author),
+	}
+	for i, lines := range pages {
+		var stream bytes.Buffer
+		stream.WriteString("BT /F1 12 Tf 72 720 Td 14 TL\n")
+		for _, line := range lines {
+			fmt.Fprintf(&stream, "(%s) Tj T*\n", line)
+		}
+		stream.WriteString("ET")
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", stream.Len(), stream.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func TestScrapePDF(t *testing.T) {
+	manual := buildPDF("Vendor Manual", "Vendor Inc",
+		[]string{"Installation", "Run the installer."},
+		[]string{},
+		[]string{"Configuration", "Edit the settings file."},
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><body><main><p>Docs</p><a href="/manual.pdf">manual</a></main></body></html>`)
+		case "/manual.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(manual)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, RateLimit: 1000, IgnoreRobots: true})
+	require.NoError(t, err)
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+
+	var pages []int
+	for _, doc := range docs {
+		if doc.URL != server.URL+"/manual.pdf" {
+			continue
+		}
+		page := doc.Metadata["page"].(int)
+		pages = append(pages, page)
+		assert.Equal(t, "Vendor Manual", doc.Title)
+		assert.Equal(t, "Vendor Inc", doc.Metadata["author"])
+		assert.Equal(t, "2024-01-02T03:04:05Z", doc.Metadata["published"])
+		assert.Equal(t, 3, doc.Metadata["pageCount"])
+		switch page {
+		case 1:
+			assert.Equal(t, "Installation Run the installer.", doc.Content)
+		case 3:
+			assert.Equal(t, "Configuration\nEdit the settings file.", doc.Markdown)
+			assert.Equal(t, 5, doc.Metadata["wordCount"])
+		}
+	}
+	assert.ElementsMatch(t, []int{1, 3}, pages, "the empty page is left out")
+
+	ids := make(map[string]bool)
+	for _, doc := range docs {
+		assert.False(t, ids[doc.ID], "document IDs are unique")
+		ids[doc.ID] = true
+	}
+}
+
+func TestIsPDF(t *testing.T) {
+	pdfURL, _ := url.Parse("https://example.com/files/Guide.PDF")
+	pageURL, _ := url.Parse("https://example.com/guide")
+
+	assert.True(t, isPDF("application/pdf", pageURL))
+	assert.True(t, isPDF("application/pdf; qs=0.001", pageURL))
+	assert.True(t, isPDF("application/octet-stream", pdfURL))
+	assert.True(t, isPDF("", pdfURL))
+	assert.False(t, isPDF("text/html; charset=utf-8", pdfURL))
+	assert.False(t, isPDF("application/octet-stream", pageURL))
+}
+
+func TestReadPDFInvalid(t *testing.T) {
+	_, err := readPDF([]byte("%PDF-1.4\nnot really a pdf"))
+	assert.ErrorContains(t, err, "invalid PDF")
+}
+
+func TestPDFDate(t *testing.T) {
+	assert.Equal(t, "2024-01-02T03:04:05+01:00", pdfDate("D:20240102030405+01'00'"))
+	assert.Equal(t, "2024-01-02T03:04:05Z", pdfDate("D:20240102030405"))
+	assert.Equal(t, "2024-01-02T00:00:00Z", pdfDate("D:20240102"))
+	assert.Equal(t, "yesterday", pdfDate("yesterday"))
+}
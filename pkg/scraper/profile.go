@@ -0,0 +1,300 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Secret is a credential given inline, or read from an environment variable
+// or a file when the scraper is created. It prints as "[redacted]" so that
+// configs can be logged safely.
+type Secret struct {
+	Value string // the secret itself
+	Env   string // name of an environment variable holding it
+	File  string // path of a file holding it, trailing newlines are trimmed
+}
+
+// String implements fmt.Stringer without revealing the secret.
+func (s Secret) String() string {
+	if s.IsZero() {
+		return ""
+	}
+	return "[redacted]"
+}
+
+// GoString implements fmt.GoStringer without revealing the secret.
+func (s Secret) GoString() string {
+	return s.String()
+}
+
+// IsZero reports whether no secret is configured.
+func (s Secret) IsZero() bool {
+	return s.Value == "" && s.Env == "" && s.File == ""
+}
+
+// resolve returns the value of the secret. Errors name the variable or file
+// but never include the value.
+func (s Secret) resolve() (string, error) {
+	switch {
+	case s.Value != "":
+		return s.Value, nil
+	case s.Env != "":
+		value, ok := os.LookupEnv(s.Env)
+		if !ok || value == "" {
+			return "", fmt.Errorf("environment variable %s is not set", s.Env)
+		}
+		return value, nil
+	case s.File != "":
+		data, err := os.ReadFile(s.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file %s: %v", s.File, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return "", nil
+}
+
+// HTTPProfile sets how requests to a set of hosts are made, for sites that
+// require authentication or a proxy. Hosts are matched like ExtractorRule
+// hosts; the first matching profile wins.
+type HTTPProfile struct {
+	Hosts       []string
+	Headers     map[string]Secret // extra request headers
+	BearerToken Secret
+	Username    string // basic auth, together with Password
+	Password    Secret
+	CookieFile  string // Netscape cookies.txt file loaded into the cookie jar
+	UserAgent   string // overrides ScraperConfig.UserAgent
+	Proxy       Secret // HTTP proxy URL, which may include credentials
+}
+
+// httpProfile is an HTTPProfile with its secrets resolved.
+type httpProfile struct {
+	hosts     []string
+	header    http.Header
+	userAgent string
+	client    *http.Client
+}
+
+// compileProfile resolves the secrets of p and builds its HTTP client.
+func compileProfile(p HTTPProfile, timeout time.Duration, defaultUserAgent string) (*httpProfile, error) {
+	profile := &httpProfile{
+		hosts:     p.Hosts,
+		header:    make(http.Header),
+		userAgent: p.UserAgent,
+	}
+	if profile.userAgent == "" {
+		profile.userAgent = defaultUserAgent
+	}
+
+	for name, secret := range p.Headers {
+		value, err := secret.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("header %s: %v", name, err)
+		}
+		profile.header.Set(name, value)
+	}
+
+	if !p.BearerToken.IsZero() {
+		token, err := p.BearerToken.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("bearer token: %v", err)
+		}
+		profile.header.Set("Authorization", "Bearer "+token)
+	}
+
+	if p.Username != "" {
+		password, err := p.Password.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("password: %v", err)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(p.Username + ":" + password))
+		profile.header.Set("Authorization", "Basic "+credentials)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !p.Proxy.IsZero() {
+		proxy, err := p.Proxy.resolve()
+		if err != nil {
+			return nil, fmt.Errorf("proxy: %v", err)
+		}
+		proxyURL, err := url.Parse(proxy)
+		if err != nil || proxyURL.Host == "" {
+			// The URL may hold credentials, so leave it out of the error.
+			return nil, errors.New("proxy: invalid URL")
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	profile.client = &http.Client{
+		Timeout:       timeout,
+		Transport:     transport,
+		CheckRedirect: profile.checkRedirect,
+	}
+
+	if p.CookieFile != "" {
+		jar, err := loadCookieFile(p.CookieFile)
+		if err != nil {
+			return nil, err
+		}
+		profile.client.Jar = jar
+	}
+
+	return profile, nil
+}
+
+// matches reports whether the profile applies to the host of u.
+func (p *httpProfile) matches(u *url.URL) bool {
+	for _, pattern := range p.hosts {
+		if matchHost(pattern, u.Hostname()) || matchHost(pattern, canonicalHost(u)) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRedirect follows up to 10 redirects like the default client, but
+// drops the profile's headers when a redirect leaves its hosts, so
+// credentials are never sent to another site.
+func (p *httpProfile) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if !p.matches(req.URL) {
+		for name := range p.header {
+			req.Header.Del(name)
+		}
+	}
+	return nil
+}
+
+// compileProfiles compiles every HTTP profile of config.
+func compileProfiles(config ScraperConfig) ([]*httpProfile, error) {
+	var profiles []*httpProfile
+	for i, p := range config.HTTPProfiles {
+		profile, err := compileProfile(p, config.Timeout, config.UserAgent)
+		if err != nil {
+			return nil, fmt.Errorf("http profile %d (%s): %v", i, strings.Join(p.Hosts, ", "), err)
+		}
+		profile.client.Transport = transportFor(config, profile.client.Transport, profile.header)
+		profiles = append(profiles, profile)
+	}
+	return profiles, nil
+}
+
+// profileFor returns the profile for the host of u, or the default profile
+// when none matches.
+func (s *Scraper) profileFor(u *url.URL) *httpProfile {
+	for _, profile := range s.profiles {
+		if profile.matches(u) {
+			return profile
+		}
+	}
+	return s.defaultProfile
+}
+
+// userAgentFor returns the User-Agent sent to the host of u, which is also
+// the one matched against its robots.txt.
+func (s *Scraper) userAgentFor(u *url.URL) string {
+	return s.profileFor(u).userAgent
+}
+
+// newRequest builds a GET request for u carrying the headers of its host's
+// profile, and returns it with the client to send it with.
+func (s *Scraper) newRequest(ctx context.Context, u *url.URL, header http.Header) (*http.Request, *http.Client, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	profile := s.profileFor(u)
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	for key, values := range profile.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("User-Agent", profile.userAgent)
+
+	return req, profile.client, nil
+}
+
+// loadCookieFile reads a cookie jar from a file in the Netscape cookies.txt
+// format written by browser extensions and curl: one cookie per line with
+// tab separated domain, subdomain flag, path, secure flag, expiry, name and
+// value. Expired cookies are dropped.
+func loadCookieFile(path string) (http.CookieJar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open cookie file: %v", err)
+	}
+	defer file.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		// curl marks HttpOnly cookies with a prefix that otherwise reads as
+		// a comment.
+		httpOnly := strings.HasPrefix(text, "#HttpOnly_")
+		text = strings.TrimPrefix(text, "#HttpOnly_")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("invalid cookie file %s: line %d has %d fields, want 7", path, line, len(fields))
+		}
+
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cookie file %s: line %d has an invalid expiry", path, line)
+		}
+
+		cookie := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+		if expiry > 0 {
+			cookie.Expires = time.Unix(expiry, 0)
+			if cookie.Expires.Before(now) {
+				continue
+			}
+		}
+
+		domain := strings.TrimPrefix(fields[0], ".")
+		if strings.EqualFold(fields[1], "TRUE") {
+			cookie.Domain = domain
+		}
+
+		scheme := "http"
+		if cookie.Secure {
+			scheme = "https"
+		}
+		jar.SetCookies(&url.URL{Scheme: scheme, Host: domain, Path: cookie.Path}, []*http.Cookie{cookie})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %v", err)
+	}
+
+	return jar, nil
+}
@@ -0,0 +1,145 @@
+package scraper
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xhad/yes/internal/models"
+)
+
+// CrawlReport lists every URL a crawl visited with what the server answered
+// and the reason it was skipped or failed.
+type CrawlReport struct {
+	StartURL string             `json:"startURL"`
+	Started  time.Time          `json:"started"`
+	Finished time.Time          `json:"finished"` // zero while the crawl is running
+	Pages    []models.CrawlPage `json:"pages"`
+}
+
+// Report returns the report of the latest crawl. A resumed crawl includes
+// the pages visited before it was interrupted.
+func (s *Scraper) Report() CrawlReport {
+	run := s.latestRun()
+	if run == nil {
+		return CrawlReport{}
+	}
+
+	run.mu.Lock()
+	finished := run.finished
+	run.mu.Unlock()
+
+	return CrawlReport{
+		StartURL: run.startURL,
+		Started:  run.started,
+		Finished: finished,
+		Pages:    run.frontier.snapshot(),
+	}
+}
+
+// Count returns the number of pages with the given status.
+func (r CrawlReport) Count(status string) int {
+	var n int
+	for _, page := range r.Pages {
+		if page.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// BrokenLinks returns the pages that answered with an error status or could
+// not be fetched at all.
+func (r CrawlReport) BrokenLinks() []models.CrawlPage {
+	var broken []models.CrawlPage
+	for _, page := range r.Pages {
+		if page.Status == models.PageFailed || page.StatusCode >= 400 {
+			broken = append(broken, page)
+		}
+	}
+	return broken
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r CrawlReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// reportColumns is the CSV header. Redirects are separated by spaces.
+var reportColumns = []string{
+	"url", "status", "status_code", "reason", "final_url", "redirects",
+	"content_type", "bytes", "latency_ms", "depth", "referrer",
+}
+
+// WriteCSV writes one row per page, preceded by a header row.
+func (r CrawlReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(reportColumns); err != nil {
+		return err
+	}
+
+	for _, page := range r.Pages {
+		err := writer.Write([]string{
+			page.URL,
+			page.Status,
+			formatStatusCode(page.StatusCode),
+			page.Reason,
+			page.FinalURL,
+			strings.Join(page.Redirects, " "),
+			page.ContentType,
+			strconv.FormatInt(page.Bytes, 10),
+			strconv.FormatInt(page.LatencyMS, 10),
+			strconv.Itoa(page.Depth),
+			page.Referrer,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteText writes the pages as a table for the terminal.
+func (r CrawlReport) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tCODE\tTIME\tSIZE\tURL\tDETAILS")
+
+	for _, page := range r.Pages {
+		fmt.Fprintf(tw, "%s\t%s\t%dms\t%d\t%s\t%s\n",
+			page.Status, formatStatusCode(page.StatusCode), page.LatencyMS, page.Bytes, page.URL, pageDetails(page))
+	}
+
+	return tw.Flush()
+}
+
+func formatStatusCode(code int) string {
+	if code == 0 {
+		return ""
+	}
+	return strconv.Itoa(code)
+}
+
+// pageDetails summarizes why a page was skipped or failed, where it was
+// redirected to and, for broken links, where it was linked from.
+func pageDetails(page models.CrawlPage) string {
+	var details []string
+	if page.Reason != "" {
+		details = append(details, page.Reason)
+	}
+	if len(page.Redirects) > 0 {
+		details = append(details, "redirected to "+page.Redirects[len(page.Redirects)-1])
+	}
+	if page.Referrer != "" && (page.Status == models.PageFailed || page.StatusCode >= 400) {
+		details = append(details, "linked from "+page.Referrer)
+	}
+	return strings.Join(details, "; ")
+}
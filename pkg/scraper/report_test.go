@@ -0,0 +1,137 @@
+package scraper
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
+)
+
+// crawlReportSite crawls a site with a redirect, a broken link, a server
+// error and a page disallowed by robots.txt, and returns its report.
+func crawlReportSite(t *testing.T) (CrawlReport, string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, `<html><body><main><p>Home</p>
+			<a href="/old">old</a> <a href="/missing">missing</a>
+			<a href="/broken">broken</a> <a href="/private">private</a>
+		</main></body></html>`)
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/older", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/older", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/new", http.StatusFound)
+	})
+	mux.HandleFunc("/new", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><main><p>New</p></main></body></html>`)
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		RateLimit: 1000,
+		Retry:     RetryPolicy{MaxAttempts: 1},
+	})
+	require.NoError(t, err)
+
+	_, err = s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	return s.Report(), server.URL
+}
+
+func TestCrawlReport(t *testing.T) {
+	report, base := crawlReportSite(t)
+
+	assert.Equal(t, base+"/", report.StartURL)
+	assert.False(t, report.Finished.Before(report.Started))
+
+	pages := make(map[string]models.CrawlPage)
+	for _, page := range report.Pages {
+		pages[page.URL] = page
+	}
+	require.Len(t, pages, 5)
+
+	home := pages[base+"/"]
+	assert.Equal(t, models.PageFetched, home.Status)
+	assert.Equal(t, http.StatusOK, home.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", home.ContentType)
+	assert.Positive(t, home.Bytes)
+	assert.True(t, home.Root)
+
+	old := pages[base+"/old"]
+	assert.Equal(t, models.PageFetched, old.Status)
+	assert.Equal(t, []string{base + "/older", base + "/new"}, old.Redirects)
+	assert.Equal(t, base+"/new", old.FinalURL)
+
+	missing := pages[base+"/missing"]
+	assert.Equal(t, models.PageFailed, missing.Status)
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+	assert.Contains(t, missing.Reason, "404")
+	assert.Equal(t, base+"/", missing.Referrer)
+
+	assert.Equal(t, http.StatusInternalServerError, pages[base+"/broken"].StatusCode)
+
+	private := pages[base+"/private"]
+	assert.Equal(t, models.PageSkipped, private.Status)
+	assert.Equal(t, SkipDisallowed, private.Reason)
+	assert.Zero(t, private.StatusCode)
+
+	assert.Equal(t, 2, report.Count(models.PageFetched))
+	var broken []string
+	for _, page := range report.BrokenLinks() {
+		broken = append(broken, page.URL)
+	}
+	assert.ElementsMatch(t, []string{base + "/missing", base + "/broken"}, broken)
+}
+
+func TestCrawlReportExport(t *testing.T) {
+	report, base := crawlReportSite(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteJSON(&buf))
+	var decoded CrawlReport
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, report.Pages, decoded.Pages)
+
+	buf.Reset()
+	require.NoError(t, report.WriteCSV(&buf))
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, len(report.Pages)+1)
+	assert.Equal(t, reportColumns, rows[0])
+	for _, row := range rows[1:] {
+		if row[0] == base+"/old" {
+			assert.Equal(t, base+"/older "+base+"/new", row[5])
+			assert.Equal(t, "200", row[2])
+		}
+	}
+
+	buf.Reset()
+	require.NoError(t, report.WriteText(&buf))
+	text := buf.String()
+	assert.True(t, strings.HasPrefix(text, "STATUS"))
+	assert.Contains(t, text, "linked from "+base+"/")
+	assert.Contains(t, text, "redirected to "+base+"/new")
+}
@@ -0,0 +1,161 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried. Transient network
+// errors, 408 and 5xx responses are retried with exponential backoff and
+// jitter. 429 and 503 responses wait for their Retry-After header when present.
+// Other 4xx responses are permanent and returned straight away.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts per request, 1 disables retries
+	BaseDelay   time.Duration // backoff before the first retry, doubled after each attempt
+	MaxDelay    time.Duration // upper bound for the backoff and for Retry-After
+}
+
+// withDefaults fills in the zero fields of p.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+	if p.BaseDelay == 0 {
+		p.BaseDelay = 500 * time.Millisecond
+	}
+	if p.MaxDelay == 0 {
+		p.MaxDelay = 30 * time.Second
+	}
+	return p
+}
+
+// backoff returns the delay before retry number attempt, counting from 1.
+// The delay grows exponentially and is spread over its upper half so that
+// workers failing together do not retry together.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// retryable reports whether a response with the given status code is worth
+// retrying.
+func retryable(statusCode int) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests:
+		return true
+	}
+	return statusCode >= 500 && statusCode != http.StatusNotImplemented
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP
+// date. It returns false if the header is missing or invalid.
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// transientError reports whether err is a network failure that may not
+// happen again: a timeout, a refused or reset connection, or a connection
+// closed before the whole response arrived. Client timeouts count even
+// though they wrap context.DeadlineExceeded like a done ctx does.
+func transientError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// retryDelay decides whether the outcome of an attempt should be retried and
+// how long to wait first. Only transient errors are retried, and nothing is
+// once ctx is done.
+func (p RetryPolicy) retryDelay(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, false
+	}
+
+	if err != nil {
+		return p.backoff(attempt), transientError(err)
+	}
+
+	if !retryable(resp.StatusCode) {
+		return 0, false
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			if delay > p.MaxDelay {
+				delay = p.MaxDelay
+			}
+			return delay, true
+		}
+	}
+	return p.backoff(attempt), true
+}
+
+// get issues a rate limited GET request for urlStr with the given extra
+// headers, which may be nil, retrying transient failures according to the
+// retry policy. The last response is returned when retries run out, so the
+// caller still sees its status code.
+func (s *Scraper) get(ctx context.Context, urlStr string, header http.Header) (*http.Response, error) {
+	policy := s.config.Retry
+
+	for attempt := 1; ; attempt++ {
+		resp, err := s.do(ctx, urlStr, header)
+
+		delay, retry := policy.retryDelay(ctx, attempt, resp, err)
+		if !retry {
+			return resp, err
+		}
+
+		if err != nil {
+			log.Printf("Retrying %s in %v after error: %v", urlStr, delay.Round(time.Millisecond), err)
+		} else {
+			log.Printf("Retrying %s in %v after status %d", urlStr, delay.Round(time.Millisecond), resp.StatusCode)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
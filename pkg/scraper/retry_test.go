@@ -0,0 +1,273 @@
+package scraper
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFlakyServer answers the first failures requests with status and the
+// given headers, then serves a page. It counts every request.
+func newFlakyServer(t *testing.T, failures int32, status int, header http.Header) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		if atomic.AddInt32(&requests, 1) <= failures {
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte("<html><body><main><p>ok</p></main></body></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestRetryTransientStatus(t *testing.T) {
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusRequestTimeout} {
+		server, requests := newFlakyServer(t, 2, status, nil)
+
+		s, err := NewWithConfig(ScraperConfig{
+			BaseURL:   server.URL,
+			RateLimit: 1000,
+			Retry:     RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+		})
+		require.NoError(t, err)
+
+		docs, err := s.Scrape(server.URL)
+		require.NoError(t, err, "status %d", status)
+		assert.Len(t, docs, 1)
+		assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	server, requests := newFlakyServer(t, 100, http.StatusBadGateway, nil)
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		RateLimit: 1000,
+		Retry:     RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	_, err = s.Scrape(server.URL)
+	assert.ErrorContains(t, err, "502")
+	assert.Equal(t, int32(4), atomic.LoadInt32(requests))
+}
+
+func TestRetryPermanentStatus(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusForbidden, http.StatusGone, http.StatusNotImplemented} {
+		server, requests := newFlakyServer(t, 100, status, nil)
+
+		s, err := NewWithConfig(ScraperConfig{
+			BaseURL:   server.URL,
+			RateLimit: 1000,
+			Retry:     RetryPolicy{MaxAttempts: 5, BaseDelay: time.Millisecond},
+		})
+		require.NoError(t, err)
+
+		_, err = s.Scrape(server.URL)
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(requests), "status %d", status)
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	server, requests := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		RateLimit: 1000,
+		// A long backoff shows that Retry-After is used instead.
+		Retry: RetryPolicy{MaxAttempts: 2, BaseDelay: time.Minute, MaxDelay: time.Minute},
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	docs, err := s.Scrape(server.URL)
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestRetryAfterCappedByMaxDelay(t *testing.T) {
+	server, _ := newFlakyServer(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"3600"}})
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		RateLimit: 1000,
+		Retry:     RetryPolicy{MaxAttempts: 2, MaxDelay: 10 * time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = s.Scrape(server.URL)
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	delay, ok := retryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, delay)
+
+	delay, ok = retryAfter("Wed, 01 May 2024 12:00:30 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, delay)
+
+	delay, ok = retryAfter("Wed, 01 May 2024 11:00:00 GMT", now)
+	assert.True(t, ok)
+	assert.Zero(t, delay)
+
+	for _, value := range []string{"", "-1", "soon"} {
+		_, ok = retryAfter(value, now)
+		assert.False(t, ok, value)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}.withDefaults()
+
+	for attempt, max := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		for i := 0; i < 20; i++ {
+			delay := p.backoff(attempt)
+			assert.GreaterOrEqual(t, delay, max/2)
+			assert.LessOrEqual(t, delay, max)
+		}
+	}
+}
+
+func TestRetryClientTimeout(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		// The first request hangs past the client timeout
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte("<html><body><main><p>ok</p></main></body></html>"))
+	}))
+	t.Cleanup(server.Close)
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		RateLimit: 1000,
+		Timeout:   100 * time.Millisecond,
+		Retry:     RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL)
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestRetryConnectionReset(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		// Reset the connection of the first request without answering
+		if atomic.AddInt32(&requests, 1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			require.NoError(t, err)
+			conn.(*net.TCPConn).SetLinger(0)
+			conn.Close()
+			return
+		}
+		w.Write([]byte("<html><body><main><p>ok</p></main></body></html>"))
+	}))
+	t.Cleanup(server.Close)
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		RateLimit: 1000,
+		Retry:     RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL)
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+// failingTransport fails every request with err and counts them.
+type failingTransport struct {
+	err      error
+	requests int32
+}
+
+func (t *failingTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return nil, t.err
+}
+
+func TestRetryPermanentError(t *testing.T) {
+	transport := &failingTransport{err: errors.New("x509: certificate signed by unknown authority")}
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:      "https://example.com",
+		RateLimit:    1000,
+		IgnoreRobots: true,
+		Transport:    transport,
+		Retry:        RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond},
+	})
+	require.NoError(t, err)
+
+	_, err = s.Scrape("https://example.com")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&transport.requests))
+}
+
+func TestTransientError(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		transient bool
+	}{
+		{&net.OpError{Op: "read", Err: syscall.ECONNRESET}, true},
+		{&net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{fmt.Errorf("reading body: %w", io.ErrUnexpectedEOF), true},
+		{&net.DNSError{Err: "no such host", Name: "example.invalid", IsTimeout: true}, true},
+		{&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, false},
+		{errors.New("unsupported protocol scheme"), false},
+	} {
+		assert.Equal(t, tc.transient, transientError(tc.err), "%v", tc.err)
+	}
+}
@@ -0,0 +1,337 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	allow   bool
+	length  int // length of the original pattern, used for precedence
+	pattern *regexp.Regexp
+}
+
+// robotsGroup holds the rules that apply to one set of user agents.
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// robotsRules is a parsed robots.txt file.
+type robotsRules struct {
+	groups   []*robotsGroup
+	sitemaps []string
+	// disallowAll is set when robots.txt could not be fetched because of a
+	// server error, in which case the host must be treated as off limits.
+	disallowAll bool
+}
+
+// parseRobots parses a robots.txt body following the rules described in
+// RFC 9309: groups start with one or more User-agent lines, paths support the
+// "*" and "$" wildcards, and the longest matching rule wins.
+func parseRobots(r io.Reader) *robotsRules {
+	rules := &robotsRules{}
+
+	var current *robotsGroup
+	inAgents := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				current = &robotsGroup{}
+				rules.groups = append(rules.groups, current)
+				inAgents = true
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, robotsRule{
+				allow:   key == "allow",
+				length:  len(value),
+				pattern: compileRobotsPattern(value),
+			})
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			rules.sitemaps = append(rules.sitemaps, value)
+		default:
+			inAgents = false
+		}
+	}
+
+	return rules
+}
+
+// compileRobotsPattern turns a robots.txt path pattern into an anchored
+// regular expression.
+func compileRobotsPattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+
+	expr := "^" + strings.Join(parts, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// group returns the group that applies to userAgent: the one naming the
+// longest matching product token, falling back to "*". Groups naming the same
+// agent are merged.
+func (r *robotsRules) group(userAgent string) *robotsGroup {
+	token := strings.ToLower(userAgent)
+	if i := strings.IndexAny(token, "/ "); i >= 0 {
+		token = token[:i]
+	}
+
+	var (
+		best     *robotsGroup
+		bestLen  = -1
+		wildcard *robotsGroup
+	)
+
+	merge := func(dst **robotsGroup, src *robotsGroup) {
+		if *dst == nil {
+			*dst = &robotsGroup{}
+		}
+		(*dst).rules = append((*dst).rules, src.rules...)
+		if src.crawlDelay > (*dst).crawlDelay {
+			(*dst).crawlDelay = src.crawlDelay
+		}
+	}
+
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			switch {
+			case agent == "*":
+				merge(&wildcard, g)
+			case strings.HasPrefix(token, agent):
+				if len(agent) > bestLen {
+					best, bestLen = nil, len(agent)
+				}
+				if len(agent) == bestLen {
+					merge(&best, g)
+				}
+			}
+		}
+	}
+
+	if best != nil {
+		return best
+	}
+	return wildcard
+}
+
+// allowed reports whether userAgent may fetch the given path (including any
+// query string).
+func (r *robotsRules) allowed(userAgent, path string) bool {
+	if r.disallowAll {
+		return false
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+
+	g := r.group(userAgent)
+	if g == nil {
+		return true
+	}
+
+	allow, matched := true, -1
+	for _, rule := range g.rules {
+		if !rule.pattern.MatchString(path) {
+			continue
+		}
+		if rule.length > matched || (rule.length == matched && rule.allow) {
+			allow, matched = rule.allow, rule.length
+		}
+	}
+	return allow
+}
+
+// crawlDelay returns the Crawl-delay that applies to userAgent.
+func (r *robotsRules) crawlDelay(userAgent string) time.Duration {
+	if g := r.group(userAgent); g != nil {
+		return g.crawlDelay
+	}
+	return 0
+}
+
+// hostState is the per-host robots.txt cache entry.
+type hostState struct {
+	loadMu sync.Mutex
+	rules  *robotsRules
+
+	mu   sync.Mutex
+	next time.Time // earliest start of the next crawl-delayed request
+}
+
+// robotsCache fetches robots.txt at most once per host.
+type robotsCache struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{hosts: make(map[string]*hostState)}
+}
+
+func (c *robotsCache) host(key string) *hostState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	h, ok := c.hosts[key]
+	if !ok {
+		h = &hostState{}
+		c.hosts[key] = h
+	}
+	return h
+}
+
+// robotsFor returns the cache entry and robots.txt rules for the host of u,
+// fetching the rules on first use. Rules fetched while ctx was being
+// cancelled are not cached, so a later crawl fetches them again.
+func (s *Scraper) robotsFor(ctx context.Context, u *url.URL) (*hostState, *robotsRules) {
+	h := s.robots.host(u.Scheme + "://" + u.Host)
+
+	h.loadMu.Lock()
+	defer h.loadMu.Unlock()
+
+	if h.rules != nil {
+		return h, h.rules
+	}
+
+	rules := s.fetchRobots(ctx, u)
+	if ctx.Err() == nil {
+		h.rules = rules
+	}
+	return h, rules
+}
+
+// fetchRobots downloads and parses robots.txt. A missing file (any 4xx)
+// allows everything; a server or network error disallows everything.
+func (s *Scraper) fetchRobots(ctx context.Context, u *url.URL) *robotsRules {
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+
+	req, client, err := s.newRequest(ctx, robotsURL, nil)
+	if err != nil {
+		return &robotsRules{disallowAll: true}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &robotsRules{disallowAll: true}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 500 {
+		return &robotsRules{disallowAll: true}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &robotsRules{}
+	}
+
+	// Cap the body at 500 KiB as recommended by RFC 9309.
+	return parseRobots(io.LimitReader(resp.Body, 500*1024))
+}
+
+// allowedByRobots reports whether urlStr may be fetched. It always returns
+// true when IgnoreRobots is set.
+func (s *Scraper) allowedByRobots(ctx context.Context, urlStr string) bool {
+	if s.config.IgnoreRobots {
+		return true
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return false
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	_, rules := s.robotsFor(ctx, u)
+	return rules.allowed(s.userAgentFor(u), path)
+}
+
+// waitCrawlDelay blocks until the host's Crawl-delay allows another request.
+// It is applied on top of the host's rate limiter.
+func (s *Scraper) waitCrawlDelay(ctx context.Context, urlStr string) error {
+	if s.config.IgnoreRobots {
+		return nil
+	}
+
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil
+	}
+
+	h, rules := s.robotsFor(ctx, u)
+	delay := rules.crawlDelay(s.userAgentFor(u))
+	if delay == 0 {
+		return nil
+	}
+
+	// Reserve the next slot so concurrent workers queue up behind each other.
+	h.mu.Lock()
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(delay)
+	h.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(start))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
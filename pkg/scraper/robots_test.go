@@ -0,0 +1,180 @@
+package scraper
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRobots = `
+# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/public-*.html$
+Crawl-delay: 2
+
+User-agent: yes-scraper
+User-agent: other-bot
+Disallow: /internal
+Allow: /internal/docs
+Crawl-delay: 0.5
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestParseRobots(t *testing.T) {
+	rules := parseRobots(strings.NewReader(testRobots))
+
+	assert.Equal(t, []string{"https://example.com/sitemap.xml"}, rules.sitemaps)
+	require.Len(t, rules.groups, 2)
+	assert.Equal(t, []string{"yes-scraper", "other-bot"}, rules.groups[1].agents)
+
+	tests := []struct {
+		agent    string
+		path     string
+		expected bool
+	}{
+		{"SomeBot/2.0", "/docs/", true},
+		{"SomeBot/2.0", "/private/secret.html", false},
+		{"SomeBot/2.0", "/private/public-page.html", true},
+		{"SomeBot/2.0", "/private/public-page.html?x=1", false},
+		{DefaultUserAgent, "/private/secret.html", true},
+		{DefaultUserAgent, "/internal/secret", false},
+		{DefaultUserAgent, "/internal/docs/page", true},
+		{DefaultUserAgent, "/robots.txt", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.agent+tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rules.allowed(tt.agent, tt.path))
+		})
+	}
+
+	assert.Equal(t, 2*time.Second, rules.crawlDelay("SomeBot"))
+	assert.Equal(t, 500*time.Millisecond, rules.crawlDelay(DefaultUserAgent))
+}
+
+func TestScrapeHonorsRobots(t *testing.T) {
+	var (
+		mu           sync.Mutex
+		robotsHits   int
+		userAgents   []string
+		privateFetch bool
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		userAgents = append(userAgents, r.UserAgent())
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/robots.txt":
+			mu.Lock()
+			robotsHits++
+			mu.Unlock()
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case "/":
+			fmt.Fprint(w, `<html><body><main>Home <a href="/private/a.html">a</a> <a href="/public.html">b</a></main></body></html>`)
+		case "/private/a.html":
+			mu.Lock()
+			privateFetch = true
+			mu.Unlock()
+			fmt.Fprint(w, `<html><body><main>Private</main></body></html>`)
+		default:
+			fmt.Fprint(w, `<html><body><main>Public</main></body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	var skipped []string
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  1,
+		RateLimit: 1000,
+		UserAgent: "test-agent/1.0",
+		OnSkip: func(url, reason string) {
+			mu.Lock()
+			skipped = append(skipped, url)
+			mu.Unlock()
+		},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+
+	assert.Len(t, docs, 2)
+	assert.False(t, privateFetch)
+	assert.Equal(t, []string{server.URL + "/private/a.html"}, skipped)
+	assert.Equal(t, 1, robotsHits)
+	for _, ua := range userAgents {
+		assert.Equal(t, "test-agent/1.0", ua)
+	}
+}
+
+func TestScrapeIgnoreRobots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nDisallow: /\n")
+			return
+		}
+		fmt.Fprint(w, `<html><body><main>Home</main></body></html>`)
+	}))
+	defer server.Close()
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, RateLimit: 1000})
+	require.NoError(t, err)
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+
+	s, err = NewWithConfig(ScraperConfig{BaseURL: server.URL, RateLimit: 1000, IgnoreRobots: true})
+	require.NoError(t, err)
+	docs, err = s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+}
+
+func TestRobotsServerErrorDisallows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `<html><body><main>Home</main></body></html>`)
+	}))
+	defer server.Close()
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, RateLimit: 1000})
+	require.NoError(t, err)
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+}
+
+func TestCrawlDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			fmt.Fprint(w, "User-agent: *\nCrawl-delay: 0.2\n")
+			return
+		}
+		fmt.Fprint(w, `<html><body><main>Home <a href="/a">a</a> <a href="/b">b</a></main></body></html>`)
+	}))
+	defer server.Close()
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, MaxDepth: 1, RateLimit: 1000, Workers: 3})
+	require.NoError(t, err)
+
+	start := time.Now()
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	assert.Len(t, docs, 3)
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
@@ -0,0 +1,171 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/xhad/yes/internal/glob"
+	"golang.org/x/time/rate"
+)
+
+// CrawlScope limits the crawl to a set of hosts and paths. Include and
+// Exclude rules are matched against the URL path. They are globs such as
+// "/v1/**" or "*.pdf", or regular expressions when prefixed with "re:", as
+// in "re:^/v[0-9]+/legacy/".
+type CrawlScope struct {
+	Hosts        []string // allowed hosts, "*.example.com" also matches subdomains; defaults to the BaseURL host
+	PathPrefixes []string // when set, the path must start with one of them
+	Include      []string // when set, the path must match one of them
+	Exclude      []string // paths matching any of them are skipped
+}
+
+// HostRateLimit overrides ScraperConfig.RateLimit for a set of hosts, which
+// are matched like ExtractorRule hosts.
+type HostRateLimit struct {
+	Hosts     []string
+	RateLimit float64 // requests per second
+}
+
+// pathRule matches a URL path.
+type pathRule func(path string) bool
+
+// compiledScope is a CrawlScope with its rules parsed.
+type compiledScope struct {
+	hosts        []string
+	pathPrefixes []string
+	include      []pathRule
+	exclude      []pathRule
+}
+
+func compileScope(scope CrawlScope, baseHost string) (*compiledScope, error) {
+	compiled := &compiledScope{
+		hosts:        scope.Hosts,
+		pathPrefixes: scope.PathPrefixes,
+	}
+	if len(compiled.hosts) == 0 {
+		compiled.hosts = []string{baseHost}
+	}
+
+	var err error
+	if compiled.include, err = compilePathRules(scope.Include); err != nil {
+		return nil, err
+	}
+	if compiled.exclude, err = compilePathRules(scope.Exclude); err != nil {
+		return nil, err
+	}
+	return compiled, nil
+}
+
+// compilePathRule parses an include or exclude rule of a CrawlScope.
+func compilePathRule(rule string) (pathRule, error) {
+	if expr, ok := strings.CutPrefix(rule, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scope rule %q: %v", rule, err)
+		}
+		return re.MatchString, nil
+	}
+
+	pattern, err := glob.Compile(rule)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scope rule %q: %v", rule, err)
+	}
+	return pattern.Match, nil
+}
+
+func compilePathRules(rules []string) ([]pathRule, error) {
+	compiled := make([]pathRule, 0, len(rules))
+	for _, rule := range rules {
+		match, err := compilePathRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, match)
+	}
+	return compiled, nil
+}
+
+// allows reports whether u lies within the scope.
+func (c *compiledScope) allows(u *url.URL) bool {
+	if !c.allowsHost(u) {
+		return false
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+
+	if len(c.pathPrefixes) > 0 {
+		found := false
+		for _, prefix := range c.pathPrefixes {
+			if strings.HasPrefix(path, prefix) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(c.include) > 0 && !matchAnyRule(c.include, path) {
+		return false
+	}
+	return !matchAnyRule(c.exclude, path)
+}
+
+// allowsHost matches the host of u against the scope hosts. Patterns with a
+// port only match that port; patterns without one match any port.
+func (c *compiledScope) allowsHost(u *url.URL) bool {
+	host := canonicalHost(u)
+	for _, pattern := range c.hosts {
+		name := u.Hostname()
+		if strings.Contains(pattern, ":") {
+			name = host
+		}
+		if matchHost(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyRule(rules []pathRule, path string) bool {
+	for _, match := range rules {
+		if match(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// limiterFor returns the rate limiter of host, creating it on first use.
+// Every host gets its own limiter so a slow host does not hold back the
+// others.
+func (s *Scraper) limiterFor(host string) *rate.Limiter {
+	s.limitersMu.Lock()
+	defer s.limitersMu.Unlock()
+
+	if limiter, ok := s.limiters[host]; ok {
+		return limiter
+	}
+
+	limit := s.config.RateLimit
+	hostname := (&url.URL{Host: host}).Hostname()
+rules:
+	for _, rule := range s.config.HostRateLimits {
+		for _, pattern := range rule.Hosts {
+			if matchHost(pattern, hostname) || matchHost(pattern, host) {
+				limit = rule.RateLimit
+				break rules
+			}
+		}
+	}
+
+	limiter := rate.NewLimiter(rate.Limit(limit), 1)
+	s.limiters[host] = limiter
+	return limiter
+}
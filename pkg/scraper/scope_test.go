@@ -0,0 +1,171 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestCrawlScope(t *testing.T) {
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL: "https://docs.example.com",
+		Scope: CrawlScope{
+			Hosts:   []string{"docs.example.com", "*.api.example.com"},
+			Exclude: []string{"/v1/**", "re:^/internal(/|$)", "*.pdf"},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := map[string]bool{
+		"https://docs.example.com/":               true,
+		"https://DOCS.example.com/guide":          true,
+		"https://docs.example.com:8443/guide":     true,
+		"https://api.example.com/v2/users":        true,
+		"https://eu.api.example.com/v2/users":     true,
+		"https://example.com/":                    false,
+		"https://blog.example.com/":               false,
+		"https://api.example.com/v1/users":        false,
+		"https://api.example.com/v1/":             false,
+		"https://docs.example.com/v2/v1/":         true,
+		"https://docs.example.com/internal":       false,
+		"https://docs.example.com/internal/notes": false,
+		"https://docs.example.com/internals":      true,
+		"ftp://docs.example.com/":                 false,
+		"mailto:team@docs.example.com":            false,
+	}
+	for rawURL, want := range tests {
+		assert.Equal(t, want, s.shouldProcessURL(rawURL), rawURL)
+	}
+}
+
+func TestCrawlScopePathPrefixesAndInclude(t *testing.T) {
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL: "https://example.com/docs/",
+		Scope: CrawlScope{
+			PathPrefixes: []string{"/docs/", "/reference/"},
+			Include:      []string{"re:/(v2|latest)/"},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, s.shouldProcessURL("https://example.com/docs/v2/intro"))
+	assert.True(t, s.shouldProcessURL("https://example.com/reference/latest/api"))
+	assert.False(t, s.shouldProcessURL("https://example.com/docs/v1/intro"))
+	assert.False(t, s.shouldProcessURL("https://example.com/blog/v2/post"))
+	assert.False(t, s.shouldProcessURL("https://other.com/docs/v2/intro"), "hosts default to the BaseURL host")
+}
+
+func TestCrawlScopeInvalidRule(t *testing.T) {
+	_, err := NewWithConfig(ScraperConfig{
+		BaseURL: "https://example.com",
+		Scope:   CrawlScope{Exclude: []string{"re:("}},
+	})
+	assert.Error(t, err)
+
+	_, err = NewWithConfig(ScraperConfig{
+		BaseURL: "https://example.com",
+		Scope:   CrawlScope{Include: []string{"/docs/[a-z"}},
+	})
+	assert.Error(t, err)
+}
+
+func TestCrawlMultipleHosts(t *testing.T) {
+	api := newSiteServer(t, map[string][]string{
+		"/v1/old": {},
+		"/v2/new": {},
+	})
+	docs := newSiteServer(t, map[string][]string{
+		"/":      {"/guide", api.URL + "/v1/old", api.URL + "/v2/new"},
+		"/guide": {},
+	})
+
+	apiHost, _ := url.Parse(api.URL)
+	docsHost, _ := url.Parse(docs.URL)
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   docs.URL,
+		RateLimit: 1000,
+		Scope: CrawlScope{
+			Hosts:   []string{docsHost.Host, apiHost.Host},
+			Exclude: []string{"/v1/**"},
+		},
+	})
+	require.NoError(t, err)
+
+	result, err := s.Scrape(docs.URL + "/")
+	require.NoError(t, err)
+
+	var urls []string
+	for _, doc := range result {
+		urls = append(urls, doc.URL)
+	}
+	sort.Strings(urls)
+	expected := []string{docs.URL + "/", docs.URL + "/guide", api.URL + "/v2/new"}
+	sort.Strings(expected)
+	assert.Equal(t, expected, urls)
+}
+
+func TestPerHostRateLimits(t *testing.T) {
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   "https://docs.example.com",
+		RateLimit: 5,
+		HostRateLimits: []HostRateLimit{
+			{Hosts: []string{"api.example.com"}, RateLimit: 0.5},
+			{Hosts: []string{"*.example.com"}, RateLimit: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	docs := s.limiterFor("docs.example.com")
+	assert.Same(t, docs, s.limiterFor("docs.example.com"))
+	assert.Equal(t, rate.Limit(1), docs.Limit())
+	assert.Equal(t, rate.Limit(0.5), s.limiterFor("api.example.com:443").Limit())
+	assert.Equal(t, rate.Limit(5), s.limiterFor("other.org").Limit())
+}
+
+func TestPerHostRateLimitsIndependent(t *testing.T) {
+	// With a single limiter of one request per second the second host would
+	// have to wait; each host has its own budget instead.
+	s, err := NewWithConfig(ScraperConfig{BaseURL: "https://docs.example.com", RateLimit: 1})
+	require.NoError(t, err)
+
+	assert.True(t, s.limiterFor("docs.example.com").Allow())
+	assert.True(t, s.limiterFor("api.example.com").Allow())
+	assert.False(t, s.limiterFor("docs.example.com").Allow())
+}
+
+func TestInvalidHostRateLimits(t *testing.T) {
+	for _, limit := range []float64{0, -1} {
+		_, err := NewWithConfig(ScraperConfig{
+			BaseURL:        "https://docs.example.com",
+			HostRateLimits: []HostRateLimit{{Hosts: []string{"api.example.com"}, RateLimit: limit}},
+		})
+		assert.ErrorContains(t, err, "rate limit of api.example.com must be positive")
+	}
+	_, err := NewWithConfig(ScraperConfig{BaseURL: "https://docs.example.com", RateLimit: -1})
+	assert.Error(t, err)
+
+	// A limiter that can never allow a request fails the fetch rather than
+	// blocking it
+	s, err := NewWithConfig(ScraperConfig{BaseURL: "https://docs.example.com"})
+	require.NoError(t, err)
+	s.limiters["docs.example.com"] = rate.NewLimiter(0, 0)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.do(context.Background(), "https://docs.example.com/", nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "rate limit of docs.example.com")
+	case <-time.After(2 * time.Second):
+		t.Fatal("fetch blocked on the rate limiter")
+	}
+}
@@ -1,35 +1,95 @@
 package scraper
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/xhad/yes/internal/models"
+	"github.com/xhad/yes/pkg/warc"
 	"golang.org/x/time/rate"
 )
 
+// DefaultUserAgent is used when ScraperConfig.UserAgent is empty.
+const DefaultUserAgent = "yes-scraper/1.0 (+https://github.com/xhad/yes)"
+
+// Reasons passed to OnSkip.
+const (
+	SkipDisallowed  = "disallowed by robots.txt"
+	SkipNotModified = "not modified"
+	SkipDuplicate   = "duplicate"
+	SkipUnsupported = "unsupported content type"
+)
+
+// errNotModified is returned by fetch when the server answers a conditional
+// request with 304 Not Modified.
+var errNotModified = errors.New("not modified")
+
+// PageCache looks up what a previous crawl stored for a page. The vector
+// store implements it so re-crawls only download pages that changed. Pages
+// are looked up by the URL they are requested under, so a cache must also
+// find documents stored under their final or canonical URL by the
+// "requestURL" in their metadata.
+type PageCache interface {
+	CachedPage(ctx context.Context, url string) (models.CachedPage, bool, error)
+}
+
 type ScraperConfig struct {
-	BaseURL           string
-	MaxDepth          int
-	RateLimit         float64 // requests per second
-	IgnorePatterns    []string
-	AllowedExtensions []string
-	Timeout           time.Duration
-	OnProgress        func(url string) // Add progress callback
+	BaseURL            string
+	MaxDepth           int
+	RateLimit          float64  // requests per second
+	IgnorePatterns     []string // URLs containing any of them are skipped
+	AllowedExtensions  []string
+	Timeout            time.Duration
+	MaxBodySize        int64            // bytes of a response read or archived, 64 MB if zero
+	Scope              CrawlScope       // hosts and paths to crawl, the BaseURL host by default
+	HostRateLimits     []HostRateLimit  // per-host overrides of RateLimit, first match wins
+	Workers            int              // number of concurrent fetch workers
+	MaxQueueSize       int              // maximum number of URLs waiting in the frontier
+	UserAgent          string           // sent with every request and matched against robots.txt
+	HTTPProfiles       []HTTPProfile    // per-host headers, credentials and proxies, first match wins
+	IgnoreRobots       bool             // skip robots.txt checks, for sites we own
+	UseSitemaps        bool             // seed the crawl from the site's sitemaps
+	TrackingParams     []string         // query parameters dropped from URLs, DefaultTrackingParams if nil
+	Retry              RetryPolicy      // retries for transient fetch failures
+	PageCache          PageCache        // enables conditional requests when set
+	Checkpoint         CheckpointStore  // saves the crawl state when set
+	CheckpointInterval time.Duration    // how often the crawl state is saved
+	Resume             bool             // continue from the checkpoint of the same start URL
+	Extractors         []ExtractorRule  // per-host content extractors, first match wins
+	DefaultExtractor   ContentExtractor // used when no rule matches the host
+	OnProgress         func(url string) // called concurrently from workers
+	OnSkip             func(url string, reason string)
+
+	// Archive records every request and response of the crawl when set.
+	Archive *warc.Writer
+	// Transport is used instead of the network when set, such as a
+	// warc.Replay to repeat an archived crawl offline.
+	Transport http.RoundTripper
 }
 
 type Scraper struct {
-	config   ScraperConfig
-	client   *http.Client
-	visited  map[string]bool
-	limiter  *rate.Limiter
-	baseHost string
+	config         ScraperConfig
+	defaultProfile *httpProfile // for hosts without an HTTPProfile
+	profiles       []*httpProfile
+	limitersMu     sync.Mutex
+	limiters       map[string]*rate.Limiter // per host
+	robots         *robotsCache
+	scope          *compiledScope
+	runMu          sync.Mutex
+	run            *crawlRun // latest crawl
 }
 
 func NewWithConfig(config ScraperConfig) (*Scraper, error) {
@@ -39,11 +99,46 @@ func NewWithConfig(config ScraperConfig) (*Scraper, error) {
 	if config.MaxDepth == 0 {
 		config.MaxDepth = 3
 	}
+	if config.MaxBodySize == 0 {
+		config.MaxBodySize = 64 << 20
+	}
 	if config.RateLimit == 0 {
 		config.RateLimit = 2 // 2 requests per second by default
 	}
 	if len(config.AllowedExtensions) == 0 {
-		config.AllowedExtensions = []string{".html", ".htm", "/", ""}
+		config.AllowedExtensions = []string{".html", ".htm", ".pdf", "/", ""}
+	}
+	if config.Workers == 0 {
+		config.Workers = 4
+	}
+	if config.MaxQueueSize == 0 {
+		config.MaxQueueSize = 10000
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = DefaultUserAgent
+	}
+	if config.DefaultExtractor == nil {
+		config.DefaultExtractor = &SelectorExtractor{Include: defaultContentSelectors}
+	}
+	if config.CheckpointInterval == 0 {
+		config.CheckpointInterval = 30 * time.Second
+	}
+	config.Retry = config.Retry.withDefaults()
+
+	// A limiter with no rate never lets a request through
+	if config.RateLimit < 0 {
+		return nil, fmt.Errorf("rate limit must be positive, got %v", config.RateLimit)
+	}
+	if config.Workers < 0 {
+		return nil, fmt.Errorf("number of workers must be positive, got %d", config.Workers)
+	}
+	if config.MaxQueueSize < 0 {
+		return nil, fmt.Errorf("max queue size must be positive, got %d", config.MaxQueueSize)
+	}
+	for _, rule := range config.HostRateLimits {
+		if rule.RateLimit <= 0 {
+			return nil, fmt.Errorf("rate limit of %s must be positive, got %v", strings.Join(rule.Hosts, ", "), rule.RateLimit)
+		}
 	}
 
 	parsedURL, err := url.Parse(config.BaseURL)
@@ -51,14 +146,30 @@ func NewWithConfig(config ScraperConfig) (*Scraper, error) {
 		return nil, err
 	}
 
+	scope, err := compileScope(config.Scope, canonicalHost(parsedURL))
+	if err != nil {
+		return nil, err
+	}
+
+	profiles, err := compileProfiles(config)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Scraper{
 		config: config,
-		client: &http.Client{
-			Timeout: config.Timeout,
+		defaultProfile: &httpProfile{
+			header:    make(http.Header),
+			userAgent: config.UserAgent,
+			client: &http.Client{
+				Timeout:   config.Timeout,
+				Transport: transportFor(config, http.DefaultTransport, nil),
+			},
 		},
-		visited:  make(map[string]bool),
-		limiter:  rate.NewLimiter(rate.Limit(config.RateLimit), 1),
-		baseHost: parsedURL.Host,
+		profiles: profiles,
+		limiters: make(map[string]*rate.Limiter),
+		robots:   newRobotsCache(),
+		scope:    scope,
 	}, nil
 }
 
@@ -75,8 +186,12 @@ func (s *Scraper) shouldProcessURL(urlStr string) bool {
 		return false
 	}
 
-	// Check if URL is from the same host
-	if parsedURL.Host != s.baseHost {
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return false
+	}
+
+	// Check hosts, path prefixes and include and exclude rules
+	if !s.scope.allows(parsedURL) {
 		return false
 	}
 
@@ -122,92 +237,360 @@ func (s *Scraper) cleanContent(content string) string {
 	return strings.TrimSpace(content)
 }
 
-func (s *Scraper) extractMainContent(doc *goquery.Document) string {
-	// Try to find main content area
-	selectors := []string{
-		"main",
-		"article",
-		".content",
-		"#content",
-		".documentation",
-		"#documentation",
-	}
-
-	var content string
-	for _, selector := range selectors {
-		if selected := doc.Find(selector); selected.Length() > 0 {
-			content = selected.Text()
-			break
-		}
-	}
+// extractMainContent returns the page's main content, selected by the
+// extractor configured for the page's host.
+func (s *Scraper) extractMainContent(doc *goquery.Document, host string) *goquery.Selection {
+	content := s.extractorFor(host).Extract(doc)
 
 	// Fallback to body if no main content found
-	if content == "" {
-		content = doc.Find("body").Text()
+	if strings.TrimSpace(content.Text()) == "" {
+		content = doc.Find("body")
 	}
 
-	return s.cleanContent(content)
+	return content
 }
 
 func (s *Scraper) Scrape(url string) ([]models.Document, error) {
-	var documents []models.Document
-	err := s.scrapeRecursive(url, 0, &documents)
-	return documents, err
+	return s.ScrapeContext(context.Background(), url)
 }
-func (s *Scraper) scrapeRecursive(urlStr string, depth int, documents *[]models.Document) error {
-	if depth > s.config.MaxDepth || s.visited[urlStr] {
-		return nil
-	}
 
-	if !s.shouldProcessURL(urlStr) {
-		return nil
+// ScrapeContext crawls from url until the crawl completes or ctx is done.
+// When ctx is cancelled or its deadline passes, no new pages are fetched and
+// the documents gathered so far are returned together with an error wrapping
+// ctx.Err().
+func (s *Scraper) ScrapeContext(ctx context.Context, url string) ([]models.Document, error) {
+	var (
+		mu        sync.Mutex
+		documents []models.Document
+	)
+	err := s.crawl(ctx, url, func(doc models.Document) {
+		mu.Lock()
+		documents = append(documents, doc)
+		mu.Unlock()
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return documents, fmt.Errorf("scrape interrupted: %w", ctxErr)
 	}
+	return documents, err
+}
 
-	s.visited[urlStr] = true
-	if s.config.OnProgress != nil {
-		s.config.OnProgress(urlStr)
+// ScrapeStream crawls from url in the background and sends each document on
+// the returned channel as soon as it is extracted. The channel is unbuffered
+// beyond one slot per worker, so a slow consumer pauses the crawl instead of
+// letting documents pile up in memory. Once the document channel is closed
+// the error channel yields the crawl result, which follows the same rules as
+// ScrapeContext.
+func (s *Scraper) ScrapeStream(ctx context.Context, url string) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document, s.config.Workers)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+
+		err := s.crawl(ctx, url, func(doc models.Document) {
+			select {
+			case docs <- doc:
+			case <-ctx.Done():
+			}
+		})
+		close(docs)
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("scrape interrupted: %w", ctxErr)
+		}
+		errc <- err
+	}()
+
+	return docs, errc
+}
+
+// fetch downloads a single page and returns the extracted documents along
+// with the absolute URLs of every link found on it. HTML pages give a single
+// document, PDF files one per page and plain text files one holding their
+// text; other content types yield an error wrapping errUnsupportedContent.
+// When cached is not nil the request is conditional and errNotModified is
+// returned if the page has not changed. What the server answered is recorded
+// in page, also when an error is returned.
+//
+// The document URL is the page's <link rel="canonical"> when it points into
+// the crawl scope, and otherwise the URL the request ended up at after
+// redirects.
+func (s *Scraper) fetch(ctx context.Context, urlStr string, depth int, cached *models.CachedPage, page *models.CrawlPage) ([]models.Document, []string, error) {
+	header := make(http.Header)
+	if cached != nil {
+		if cached.ETag != "" {
+			header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			header.Set("If-Modified-Since", cached.LastModified)
+		}
 	}
 
-	// Apply rate limiting
-	err := s.limiter.Wait(context.Background())
-	if err != nil {
-		return err
+	// Time the last request sent, which leaves out rate limiting, retry
+	// delays and earlier redirect hops.
+	var (
+		sentMu sync.Mutex
+		sent   time.Time
+	)
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GetConn: func(string) {
+			sentMu.Lock()
+			sent = time.Now()
+			sentMu.Unlock()
+		},
+	})
+	latency := func() int64 {
+		sentMu.Lock()
+		defer sentMu.Unlock()
+		if sent.IsZero() {
+			// Transports that do not use connections, like a replay
+			return 0
+		}
+		return time.Since(sent).Milliseconds()
 	}
 
-	resp, err := s.client.Get(urlStr)
+	resp, err := s.get(ctx, urlStr, header)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
+	page.StatusCode = resp.StatusCode
+	page.Redirects = redirectChain(resp)
+	page.ContentType = resp.Header.Get("Content-Type")
+	if resp.ContentLength > 0 {
+		page.Bytes = resp.ContentLength
+	}
+	page.LatencyMS = latency()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		return nil, nil, errNotModified
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("received status code %d for URL: %s", resp.StatusCode, urlStr)
+		return nil, nil, fmt.Errorf("received status code %d for URL: %s", resp.StatusCode, urlStr)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	body := &countingReader{r: io.LimitReader(resp.Body, s.config.MaxBodySize+1)}
+	finalURL := resp.Request.URL
+	docURL, err := s.normalizeURL(finalURL.String())
 	if err != nil {
-		return err
+		docURL = urlStr
+	}
+	contentType := resp.Header.Get("Content-Type")
+	metadata := map[string]interface{}{
+		"depth":        depth,
+		"time":         time.Now(),
+		"contentType":  contentType,
+		"lastModified": resp.Header.Get("Last-Modified"),
+		"etag":         resp.Header.Get("ETag"),
+	}
+
+	// Route the body to the extractor for its type, looking at its first
+	// bytes when the server does not say or is wrong
+	buffered := bufio.NewReaderSize(body, sniffLen)
+	head, _ := buffered.Peek(sniffLen)
+	kind, mediaType := contentKind(contentType, head, finalURL)
+	if kind == "" {
+		page.LatencyMS = latency()
+		if mediaType == "" {
+			mediaType = "unknown"
+		} else if page.ContentType == "" {
+			page.ContentType = mediaType
+		}
+		return nil, nil, fmt.Errorf("%w %s", errUnsupportedContent, mediaType)
+	}
+
+	if kind != contentHTML && docURL != urlStr {
+		page.FinalURL = docURL
+		metadata["requestURL"] = urlStr
+	}
+
+	if kind == contentPDF {
+		documents, err := s.fetchPDF(buffered, docURL, metadata)
+		page.Bytes = body.n
+		page.LatencyMS = latency()
+		return documents, nil, err
+	}
+
+	reader, encoding := decodeBody(buffered, head, contentType)
+	metadata["charset"] = encoding
+
+	if kind == contentText {
+		data, err := io.ReadAll(reader)
+		page.Bytes = body.n
+		page.LatencyMS = latency()
+		if err != nil {
+			return nil, nil, err
+		}
+		if body.n > s.config.MaxBodySize {
+			return nil, nil, s.errBodyTooLarge()
+		}
+
+		text := strings.TrimSpace(string(data))
+		metadata["wordCount"] = len(strings.Fields(text))
+		document := models.Document{
+			ID:       documentID(docURL),
+			URL:      docURL,
+			Title:    textTitle(text, finalURL),
+			Content:  s.cleanContent(text),
+			Markdown: text,
+			Metadata: metadata,
+		}
+		return []models.Document{document}, nil, nil
+	}
+
+	doc, err := goquery.NewDocumentFromReader(reader)
+	page.Bytes = body.n
+	page.LatencyMS = latency()
+	if err != nil {
+		return nil, nil, err
+	}
+	if body.n > s.config.MaxBodySize {
+		return nil, nil, s.errBodyTooLarge()
+	}
+
+	// Relative links resolve against the final URL after redirects
+	if canonical := canonicalLink(doc, finalURL); canonical != "" {
+		if canonical, err := s.normalizeURL(canonical); err == nil && s.shouldProcessURL(canonical) {
+			docURL = canonical
+		}
+	}
+	if docURL != urlStr {
+		page.FinalURL = docURL
+		metadata["requestURL"] = urlStr
+	}
+
+	// Collect links and metadata before extraction, which may remove
+	// navigation
+	links := extractLinks(doc, finalURL.String())
+	for key, value := range pageMetadata(doc, finalURL) {
+		metadata[key] = value
 	}
 
 	// Extract content
-	content := s.extractMainContent(doc)
 	title := doc.Find("title").Text()
+	main := s.extractMainContent(doc, finalURL.Hostname())
+
+	metadata["wordCount"] = wordCount(main)
+	if headings := headingOutline(main); len(headings) > 0 {
+		metadata["headings"] = headings
+	}
 
 	// Create document
 	document := models.Document{
-		URL:     urlStr,
-		Title:   title,
-		Content: content,
-		Metadata: map[string]interface{}{
-			"depth":        depth,
-			"time":         time.Now(),
-			"contentType":  resp.Header.Get("Content-Type"),
-			"lastModified": resp.Header.Get("Last-Modified"),
-		},
+		ID:       documentID(docURL),
+		URL:      docURL,
+		Title:    title,
+		Content:  s.cleanContent(main.Text()),
+		Markdown: htmlToMarkdown(main, finalURL.String()),
+		Metadata: metadata,
+		Links:    links,
+	}
+
+	return []models.Document{document}, links, nil
+}
+
+// redirectChain returns every URL resp was redirected to, in order, or nil
+// if the request was not redirected.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.URL.String()}, chain...)
 	}
-	*documents = append(*documents, document)
+	return chain
+}
+
+// errBodyTooLarge is returned for responses longer than MaxBodySize.
+func (s *Scraper) errBodyTooLarge() error {
+	return fmt.Errorf("response larger than the %d byte limit", s.config.MaxBodySize)
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
 
-	// Find and follow links
+// DocumentFromHTML builds a document from an HTML page that was not fetched
+// by the scraper, such as a file on disk, using the default content
+// extractor. Metadata is left for the caller to fill in.
+func DocumentFromHTML(r io.Reader, pageURL string) (models.Document, error) {
+	s := &Scraper{config: ScraperConfig{
+		DefaultExtractor: &SelectorExtractor{Include: defaultContentSelectors},
+	}}
+
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return models.Document{}, err
+	}
+	main := s.extractMainContent(doc, "")
+
+	return models.Document{
+		ID:       documentID(pageURL),
+		URL:      pageURL,
+		Title:    strings.TrimSpace(doc.Find("title").Text()),
+		Content:  s.cleanContent(main.Text()),
+		Markdown: htmlToMarkdown(main, pageURL),
+	}, nil
+}
+
+// documentID derives a stable document ID from its URL, so re-crawled pages
+// replace their previous version in the store.
+func documentID(urlStr string) string {
+	sum := sha256.Sum256([]byte(urlStr))
+	return hex.EncodeToString(sum[:16])
+}
+
+// do issues a single rate limited GET request for urlStr with the given
+// extra headers, which may be nil.
+func (s *Scraper) do(ctx context.Context, urlStr string, header http.Header) (*http.Response, error) {
+	u, err := url.Parse(urlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the host's rate limit. Wait also fails while ctx is live when the
+	// next slot falls after the ctx deadline. The request could never be sent
+	// in time then, so it ends with ctx rather than failing on its own.
+	host := canonicalHost(u)
+	limiter := s.limiterFor(host)
+	if err := limiter.Wait(ctx); err != nil {
+		if _, ok := ctx.Deadline(); ok && limiter.Burst() > 0 {
+			<-ctx.Done()
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, fmt.Errorf("rate limit of %s: %v", host, err)
+	}
+	if err := s.waitCrawlDelay(ctx, urlStr); err != nil {
+		return nil, err
+	}
+
+	req, client, err := s.newRequest(ctx, u, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Do(req)
+}
+
+// extractLinks resolves every <a href> on the page against pageURL.
+func extractLinks(doc *goquery.Document, pageURL string) []string {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		log.Printf("Error parsing base URL: %v", err)
+		return nil
+	}
+
+	var links []string
 	doc.Find("a[href]").Each(func(_ int, selection *goquery.Selection) {
 		href, exists := selection.Attr("href")
 		if !exists {
@@ -222,20 +605,10 @@ func (s *Scraper) scrapeRecursive(urlStr string, depth int, documents *[]models.
 
 		// Make sure the URL is absolute
 		if !absoluteURL.IsAbs() {
-			base, err := url.Parse(urlStr)
-			if err != nil {
-				log.Printf("Error parsing base URL: %v", err)
-				return
-			}
 			absoluteURL = base.ResolveReference(absoluteURL)
 		}
-
-		// Scrape the URL recursively by keeping a reference to `s`
-		scraper := s // <--- Keep a reference to `s` here!
-		if err := scraper.scrapeRecursive(absoluteURL.String(), depth+1, documents); err != nil {
-			log.Printf("Error scraping URL: %v", err)
-		}
+		links = append(links, absoluteURL.String())
 	})
 
-	return nil
+	return links
 }
@@ -1,13 +1,16 @@
 package scraper
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
 )
 
 func TestScraper(t *testing.T) {
@@ -107,3 +110,117 @@ func TestScrapeWithMockServer(t *testing.T) {
 	assert.Contains(t, doc.Content, "Test Content")
 	assert.Contains(t, doc.Content, "This is a test paragraph")
 }
+
+type mapPageCache map[string]models.CachedPage
+
+func (c mapPageCache) CachedPage(_ context.Context, url string) (models.CachedPage, bool, error) {
+	page, ok := c[url]
+	return page, ok, nil
+}
+
+// storedPageCache looks pages up among the documents of a previous crawl
+// the way the vector store does, by URL or by the URL they were requested
+// under.
+type storedPageCache []models.Document
+
+func (c storedPageCache) CachedPage(_ context.Context, url string) (models.CachedPage, bool, error) {
+	for _, doc := range c {
+		if doc.URL == url || doc.Metadata["requestURL"] == url {
+			etag, _ := doc.Metadata["etag"].(string)
+			lastModified, _ := doc.Metadata["lastModified"].(string)
+			return models.CachedPage{ETag: etag, LastModified: lastModified, Links: doc.Links}, true, nil
+		}
+	}
+	return models.CachedPage{}, false, nil
+}
+
+func TestScrapeConditionalRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`<html><body><main>Home <a href="/child">child</a></main></body></html>`))
+		case "/child":
+			if r.Header.Get("If-Modified-Since") == "Mon, 01 Jan 2024 00:00:00 GMT" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", "Tue, 02 Jan 2024 00:00:00 GMT")
+			w.Write([]byte(`<html><body><main>Child changed</main></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	var skipped []string
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:   server.URL,
+		MaxDepth:  1,
+		RateLimit: 1000,
+		PageCache: mapPageCache{
+			server.URL + "/":      {ETag: `"v1"`, Links: []string{server.URL + "/child"}},
+			server.URL + "/child": {LastModified: "Sun, 31 Dec 2023 00:00:00 GMT"},
+		},
+		OnSkip: func(url, reason string) {
+			skipped = append(skipped, url+" "+reason)
+		},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+
+	// The unchanged root is skipped but its stored links are still followed.
+	assert.Equal(t, []string{server.URL + "/ " + SkipNotModified}, skipped)
+	require.Len(t, docs, 1)
+	assert.Equal(t, server.URL+"/child", docs[0].URL)
+	assert.Equal(t, "Tue, 02 Jan 2024 00:00:00 GMT", docs[0].Metadata["lastModified"])
+	assert.Equal(t, documentID(server.URL+"/child"), docs[0].ID)
+}
+
+func TestScrapeConditionalRequestsAfterRedirect(t *testing.T) {
+	var conditional int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/new":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				atomic.AddInt32(&conditional, 1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`<html><body><main>Moved here</main></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	config := ScraperConfig{BaseURL: server.URL, RateLimit: 1000}
+	s, err := NewWithConfig(config)
+	require.NoError(t, err)
+	docs, err := s.Scrape(server.URL + "/old")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, server.URL+"/new", docs[0].URL)
+
+	// The page is stored under the URL it moved to, but found again from
+	// the URL the crawl requests
+	var skipped []string
+	config.PageCache = storedPageCache(docs)
+	config.OnSkip = func(url, reason string) {
+		skipped = append(skipped, url+" "+reason)
+	}
+	s, err = NewWithConfig(config)
+	require.NoError(t, err)
+	docs, err = s.Scrape(server.URL + "/old")
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+	assert.Equal(t, []string{server.URL + "/old " + SkipNotModified}, skipped)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conditional))
+}
@@ -0,0 +1,164 @@
+package scraper
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSitemaps bounds how many sitemap files a single crawl will read,
+	// which protects against sitemap indexes that reference each other.
+	maxSitemaps = 100
+	// maxSitemapSize is the decompressed size limit from sitemaps.org.
+	maxSitemapSize = 50 * 1024 * 1024
+)
+
+// sitemapEntry is a page listed in a sitemap.
+type sitemapEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// sitemapFile is either a <urlset> or a <sitemapindex>.
+type sitemapFile struct {
+	XMLName  xml.Name
+	URLs     []sitemapEntry `xml:"url"`
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+}
+
+// sitemapTimeLayouts are the W3C datetime formats allowed in <lastmod>.
+var sitemapTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseLastMod parses a <lastmod> value, returning the zero time if it is
+// missing or malformed.
+func parseLastMod(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range sitemapTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseSitemap decodes a sitemap or sitemap index, transparently handling
+// gzip-compressed input.
+func parseSitemap(r io.Reader) (*sitemapFile, error) {
+	br := bufio.NewReader(r)
+
+	// Detect gzip by its magic number rather than trusting the file
+	// extension or Content-Encoding header.
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var sitemap sitemapFile
+	if err := xml.NewDecoder(io.LimitReader(r, maxSitemapSize)).Decode(&sitemap); err != nil {
+		return nil, err
+	}
+	return &sitemap, nil
+}
+
+// discoverSitemaps returns the sitemaps advertised in robots.txt for the host
+// of startURL, or /sitemap.xml if there are none.
+func (s *Scraper) discoverSitemaps(ctx context.Context, startURL string) []string {
+	u, err := url.Parse(startURL)
+	if err != nil {
+		return nil
+	}
+
+	if _, rules := s.robotsFor(ctx, u); len(rules.sitemaps) > 0 {
+		return rules.sitemaps
+	}
+
+	return []string{(&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/sitemap.xml"}).String()}
+}
+
+// sitemapPages reads every discovered sitemap, following sitemap indexes,
+// and returns the listed pages.
+func (s *Scraper) sitemapPages(ctx context.Context, startURL string) []sitemapEntry {
+	var (
+		pages []sitemapEntry
+		queue = s.discoverSitemaps(ctx, startURL)
+		seen  = make(map[string]bool)
+	)
+
+	for len(queue) > 0 && len(seen) < maxSitemaps && ctx.Err() == nil {
+		sitemapURL := queue[0]
+		queue = queue[1:]
+		if seen[sitemapURL] {
+			continue
+		}
+		seen[sitemapURL] = true
+
+		sitemap, err := s.fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			log.Printf("Error reading sitemap: %v", err)
+			continue
+		}
+
+		for _, child := range sitemap.Sitemaps {
+			queue = append(queue, strings.TrimSpace(child.Loc))
+		}
+		pages = append(pages, sitemap.URLs...)
+	}
+
+	return pages
+}
+
+func (s *Scraper) fetchSitemap(ctx context.Context, sitemapURL string) (*sitemapFile, error) {
+	resp, err := s.get(ctx, sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("received status code %d for sitemap: %s", resp.StatusCode, sitemapURL)
+	}
+
+	sitemap, err := parseSitemap(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse sitemap %s: %v", sitemapURL, err)
+	}
+	return sitemap, nil
+}
+
+// seedFromSitemaps queues every sitemap page accepted by shouldProcessURL as
+// a depth 0 item. Pages with a more recent <lastmod> are fetched first.
+func (s *Scraper) seedFromSitemaps(ctx context.Context, f *frontier, startURL string) {
+	for _, page := range s.sitemapPages(ctx, startURL) {
+		loc, err := s.normalizeURL(page.Loc)
+		if err != nil || !s.shouldProcessURL(loc) {
+			continue
+		}
+
+		var priority int64
+		if lastMod := parseLastMod(page.LastMod); !lastMod.IsZero() {
+			priority = lastMod.Unix()
+		}
+		f.pushItem(crawlItem{url: loc, priority: priority})
+	}
+}
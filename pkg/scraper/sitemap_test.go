@@ -0,0 +1,134 @@
+package scraper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestParseSitemap(t *testing.T) {
+	urlset := `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>https://example.com/a</loc><lastmod>2024-01-02</lastmod></url>
+  <url><loc>https://example.com/b</loc></url>
+</urlset>`
+
+	for name, input := range map[string][]byte{
+		"plain":   []byte(urlset),
+		"gzipped": gzipBytes(t, urlset),
+	} {
+		t.Run(name, func(t *testing.T) {
+			sitemap, err := parseSitemap(bytes.NewReader(input))
+			require.NoError(t, err)
+			require.Len(t, sitemap.URLs, 2)
+			assert.Equal(t, "https://example.com/a", sitemap.URLs[0].Loc)
+			assert.Equal(t, "2024-01-02", sitemap.URLs[0].LastMod)
+		})
+	}
+
+	index := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/s1.xml</loc></sitemap>
+</sitemapindex>`
+	sitemap, err := parseSitemap(strings.NewReader(index))
+	require.NoError(t, err)
+	assert.Empty(t, sitemap.URLs)
+	require.Len(t, sitemap.Sitemaps, 1)
+	assert.Equal(t, "https://example.com/s1.xml", sitemap.Sitemaps[0].Loc)
+}
+
+func TestParseLastMod(t *testing.T) {
+	assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), parseLastMod("2024-01-02"))
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), parseLastMod("2024-01-02T03:04:05Z"))
+	assert.True(t, parseLastMod("yesterday").IsZero())
+}
+
+func TestScrapeWithSitemaps(t *testing.T) {
+	var server *httptest.Server
+	var fetched []string
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nSitemap: %s/index.xml.gz\n", server.URL)
+		case "/index.xml.gz":
+			w.Write(gzipBytes(t, fmt.Sprintf(`<sitemapindex>
+  <sitemap><loc>%[1]s/pages.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/index.xml.gz</loc></sitemap>
+</sitemapindex>`, server.URL)))
+		case "/pages.xml":
+			fmt.Fprintf(w, `<urlset>
+  <url><loc>%[1]s/old.html</loc><lastmod>2020-01-01</lastmod></url>
+  <url><loc>%[1]s/orphan.html</loc><lastmod>2024-06-01T10:00:00Z</lastmod></url>
+  <url><loc>%[1]s/undated.html</loc></url>
+  <url><loc>%[1]s/private/secret.html</loc><lastmod>2025-01-01</lastmod></url>
+  <url><loc>https://elsewhere.example/page.html</loc></url>
+</urlset>`, server.URL)
+		default:
+			fetched = append(fetched, r.URL.Path)
+			fmt.Fprintf(w, `<html><body><main>Page %s</main></body></html>`, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewWithConfig(ScraperConfig{
+		BaseURL:        server.URL,
+		MaxDepth:       1,
+		RateLimit:      1000,
+		Workers:        1,
+		UseSitemaps:    true,
+		IgnorePatterns: []string{"private"},
+	})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	assert.Len(t, docs, 4)
+
+	// The start page comes first, then sitemap pages by most recent lastmod.
+	assert.Equal(t, []string{"/", "/orphan.html", "/old.html", "/undated.html"}, fetched)
+	for _, doc := range docs {
+		assert.Equal(t, 0, doc.Metadata["depth"])
+	}
+}
+
+func TestSitemapFallback(t *testing.T) {
+	var requested bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			http.NotFound(w, r)
+		case "/sitemap.xml":
+			requested = true
+			fmt.Fprint(w, `<urlset></urlset>`)
+		default:
+			fmt.Fprint(w, `<html><body><main>Home</main></body></html>`)
+		}
+	}))
+	defer server.Close()
+
+	s, err := NewWithConfig(ScraperConfig{BaseURL: server.URL, RateLimit: 1000, UseSitemaps: true})
+	require.NoError(t, err)
+
+	docs, err := s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+	assert.True(t, requested)
+}
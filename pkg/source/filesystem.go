@@ -0,0 +1,315 @@
+// Package source provides document sources other than the web scraper. Every
+// source produces models.Document values that go through the same Processor
+// and VectorStore pipeline as scraped pages.
+package source
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/xhad/yes/internal/glob"
+	"github.com/xhad/yes/internal/models"
+	"github.com/xhad/yes/pkg/scraper"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultIncludes are the files ingested when FilesystemConfig.Include is
+// empty.
+var DefaultIncludes = []string{"*.md", "*.markdown", "*.mdx", "*.rst", "*.txt", "*.html", "*.htm"}
+
+// DefaultExcludes are skipped when FilesystemConfig.Exclude is empty.
+var DefaultExcludes = []string{"**/.git", "**/node_modules", "**/vendor"}
+
+// FilesystemConfig configures a Filesystem source.
+type FilesystemConfig struct {
+	Root        string
+	Include     []string // globs relative to Root; patterns without "/" match file names
+	Exclude     []string // globs for files and directories to skip
+	MaxFileSize int64    // larger files are skipped
+	OnProgress  func(path string)
+}
+
+// Filesystem walks a directory tree and turns Markdown, reStructuredText,
+// plain text and HTML files into documents with file:// URLs.
+type Filesystem struct {
+	config  FilesystemConfig
+	root    string
+	include []*glob.Pattern
+	exclude []*glob.Pattern
+}
+
+func NewFilesystemWithConfig(config FilesystemConfig) (*Filesystem, error) {
+	if config.Root == "" {
+		config.Root = "."
+	}
+	if len(config.Include) == 0 {
+		config.Include = DefaultIncludes
+	}
+	if len(config.Exclude) == 0 {
+		config.Exclude = DefaultExcludes
+	}
+	if config.MaxFileSize == 0 {
+		config.MaxFileSize = 10 * 1024 * 1024
+	}
+
+	root, err := filepath.Abs(config.Root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	include, err := glob.CompileAll(config.Include)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := glob.CompileAll(config.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Filesystem{
+		config:  config,
+		root:    root,
+		include: include,
+		exclude: exclude,
+	}, nil
+}
+
+// Load reads every matching file under the root.
+func (f *Filesystem) Load(ctx context.Context) ([]models.Document, error) {
+	return collect(f.Stream(ctx))
+}
+
+// Stream walks the root in the background and sends each document on the
+// returned channel as soon as it is read. Files that cannot be read are
+// logged and skipped. Once the document channel is closed the error channel
+// yields the result of the walk.
+func (f *Filesystem) Stream(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(docs)
+
+		errc <- filepath.WalkDir(f.root, func(absPath string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			relPath, err := filepath.Rel(f.root, absPath)
+			if err != nil {
+				return err
+			}
+			relPath = filepath.ToSlash(relPath)
+
+			if entry.IsDir() {
+				if relPath != "." && glob.MatchAny(f.exclude, relPath) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !glob.MatchAny(f.include, relPath) || glob.MatchAny(f.exclude, relPath) {
+				return nil
+			}
+
+			if f.config.OnProgress != nil {
+				f.config.OnProgress(relPath)
+			}
+
+			doc, err := f.loadFile(absPath, relPath)
+			if err != nil {
+				log.Printf("Error reading file: %v", err)
+				return nil
+			}
+
+			select {
+			case docs <- doc:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	return docs, errc
+}
+
+// loadFile parses a single file into a document.
+func (f *Filesystem) loadFile(absPath, relPath string) (models.Document, error) {
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return models.Document{}, err
+	}
+	if info.Size() > f.config.MaxFileSize {
+		return models.Document{}, fmt.Errorf("%s is larger than %d bytes", relPath, f.config.MaxFileSize)
+	}
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return models.Document{}, err
+	}
+
+	frontMatter, body, err := splitFrontMatter(data)
+	if err != nil {
+		return models.Document{}, fmt.Errorf("invalid front matter in %s: %v", relPath, err)
+	}
+
+	fileURL := (&url.URL{Scheme: "file", Path: filepath.ToSlash(absPath)}).String()
+	format := fileFormat(relPath)
+
+	var doc models.Document
+	switch format {
+	case "html":
+		doc, err = scraper.DocumentFromHTML(bytes.NewReader(body), fileURL)
+		if err != nil {
+			return models.Document{}, err
+		}
+	case "markdown":
+		doc = models.Document{
+			Title:    markdownTitle(string(body)),
+			Content:  string(body),
+			Markdown: string(body),
+		}
+	case "rst":
+		doc = models.Document{
+			Title:   rstTitle(string(body)),
+			Content: string(body),
+		}
+	default:
+		doc = models.Document{Content: string(body)}
+	}
+
+	doc.ID = documentID(fileURL)
+	doc.URL = fileURL
+	if title, ok := frontMatter["title"].(string); ok && title != "" {
+		doc.Title = title
+	}
+	if doc.Title == "" {
+		doc.Title = strings.TrimSuffix(path.Base(relPath), path.Ext(relPath))
+	}
+
+	doc.Metadata = map[string]interface{}{
+		"source":  "filesystem",
+		"path":    relPath,
+		"format":  format,
+		"modTime": info.ModTime(),
+		"size":    info.Size(),
+	}
+	for key, value := range frontMatter {
+		if _, exists := doc.Metadata[key]; !exists {
+			doc.Metadata[key] = value
+		}
+	}
+
+	return doc, nil
+}
+
+// splitFrontMatter separates a leading YAML front matter block delimited by
+// "---" lines from the rest of the file.
+func splitFrontMatter(data []byte) (map[string]interface{}, []byte, error) {
+	normalized := bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if !bytes.HasPrefix(normalized, []byte("---\n")) {
+		return nil, data, nil
+	}
+
+	rest := normalized[len("---\n"):]
+	end := -1
+	for _, closing := range [][]byte{[]byte("\n---\n"), []byte("\n...\n")} {
+		if i := bytes.Index(rest, closing); i >= 0 && (end < 0 || i < end) {
+			end = i
+		}
+	}
+	if end < 0 {
+		// An empty front matter block closes immediately.
+		if bytes.HasPrefix(rest, []byte("---\n")) {
+			return map[string]interface{}{}, rest[len("---\n"):], nil
+		}
+		return nil, data, nil
+	}
+
+	var frontMatter map[string]interface{}
+	if err := yaml.Unmarshal(rest[:end], &frontMatter); err != nil {
+		return nil, nil, err
+	}
+	return frontMatter, rest[end+len("\n---\n"):], nil
+}
+
+// fileFormat classifies a file by its extension.
+func fileFormat(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown", ".mdx":
+		return "markdown"
+	case ".rst":
+		return "rst"
+	case ".html", ".htm":
+		return "html"
+	default:
+		return "text"
+	}
+}
+
+var markdownHeading = regexp.MustCompile(`(?m)^#\s+(.+?)\s*#*\s*$`)
+
+// markdownTitle returns the first level one heading.
+func markdownTitle(body string) string {
+	if m := markdownHeading.FindStringSubmatch(body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// rstTitle returns the first section title, which reStructuredText marks by
+// underlining it with punctuation.
+func rstTitle(body string) string {
+	lines := strings.Split(body, "\n")
+	for i := 1; i < len(lines); i++ {
+		title := strings.TrimSpace(lines[i-1])
+		underline := strings.TrimSpace(lines[i])
+		if title != "" && isRSTUnderline(underline) && len(underline) >= len(title) {
+			return title
+		}
+	}
+	return ""
+}
+
+// isRSTUnderline reports whether line is a run of at least three of the same
+// punctuation character.
+func isRSTUnderline(line string) bool {
+	if len(line) < 3 || !strings.ContainsRune(`=-~^"'*+#:.`, rune(line[0])) {
+		return false
+	}
+	return strings.Count(line, line[:1]) == len(line)
+}
+
+// collect drains a document stream into a slice.
+func collect(docs <-chan models.Document, errc <-chan error) ([]models.Document, error) {
+	var documents []models.Document
+	for doc := range docs {
+		documents = append(documents, doc)
+	}
+	return documents, <-errc
+}
+
+// documentID derives a stable document ID from its URL.
+func documentID(urlStr string) string {
+	sum := sha256.Sum256([]byte(urlStr))
+	return hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,140 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
+)
+
+// writeFiles creates each file under root, making parent directories as
+// needed.
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func loadByPath(t *testing.T, config FilesystemConfig) map[string]models.Document {
+	t.Helper()
+	fs, err := NewFilesystemWithConfig(config)
+	require.NoError(t, err)
+
+	docs, err := fs.Load(context.Background())
+	require.NoError(t, err)
+
+	byPath := make(map[string]models.Document)
+	for _, doc := range docs {
+		byPath[doc.Metadata["path"].(string)] = doc
+	}
+	return byPath
+}
+
+func TestFilesystemLoad(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"README.md":                  "# Project\n\nIntro text.\n",
+		"docs/guide.md":              "---\ntitle: The Guide\ntags: [a, b]\npath: ignored\n---\n## Setup\n\nRun it.\n",
+		"docs/api.rst":               "API Reference\n=============\n\nCalls.\n",
+		"docs/page.html":             "<html><head><title>HTML Page</title></head><body><nav>menu</nav><main><h1>Hello</h1><p>World</p></main></body></html>",
+		"notes.txt":                  "plain notes",
+		"main.go":                    "package main",
+		".git/HEAD.md":               "# not content",
+		"node_modules/pkg/README.md": "# dependency",
+	})
+
+	docs := loadByPath(t, FilesystemConfig{Root: root})
+	require.Len(t, docs, 5)
+
+	readme := docs["README.md"]
+	assert.Equal(t, "Project", readme.Title)
+	assert.Equal(t, readme.Content, readme.Markdown)
+	assert.Equal(t, "markdown", readme.Metadata["format"])
+	assert.Equal(t, "filesystem", readme.Metadata["source"])
+	assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(root, "README.md")), readme.URL)
+	assert.Len(t, readme.ID, 32)
+
+	guide := docs["docs/guide.md"]
+	assert.Equal(t, "The Guide", guide.Title)
+	assert.Equal(t, "## Setup\n\nRun it.\n", guide.Content)
+	assert.Equal(t, []interface{}{"a", "b"}, guide.Metadata["tags"])
+	assert.Equal(t, "docs/guide.md", guide.Metadata["path"], "front matter must not override path")
+
+	assert.Equal(t, "API Reference", docs["docs/api.rst"].Title)
+
+	page := docs["docs/page.html"]
+	assert.Equal(t, "HTML Page", page.Title)
+	assert.Contains(t, page.Markdown, "# Hello")
+	assert.NotContains(t, page.Content, "menu")
+
+	notes := docs["notes.txt"]
+	assert.Equal(t, "notes", notes.Title)
+	assert.Equal(t, "plain notes", notes.Content)
+}
+
+func TestFilesystemIncludeExclude(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"docs/a.md":          "# A",
+		"docs/drafts/b.md":   "# B",
+		"docs/c.html":        "<p>C</p>",
+		"blog/2024/post.md":  "# Post",
+		"blog/2024/image.md": "# Image",
+	})
+
+	docs := loadByPath(t, FilesystemConfig{
+		Root:    root,
+		Include: []string{"docs/**/*.md", "blog/**/post.md"},
+		Exclude: []string{"**/drafts"},
+	})
+
+	var paths []string
+	for path := range docs {
+		paths = append(paths, path)
+	}
+	assert.ElementsMatch(t, []string{"docs/a.md", "blog/2024/post.md"}, paths)
+}
+
+func TestFilesystemStreamCancel(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"a.md": "# A", "b.md": "# B", "c.md": "# C"})
+
+	fs, err := NewFilesystemWithConfig(FilesystemConfig{Root: root})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	docs, errc := fs.Stream(ctx)
+	<-docs
+	cancel()
+
+	for range docs {
+	}
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
+
+func TestFilesystemMissingRoot(t *testing.T) {
+	_, err := NewFilesystemWithConfig(FilesystemConfig{Root: filepath.Join(t.TempDir(), "missing")})
+	assert.Error(t, err)
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	meta, body, err := splitFrontMatter([]byte("---\r\ntitle: T\r\n---\r\nBody"))
+	require.NoError(t, err)
+	assert.Equal(t, "T", meta["title"])
+	assert.Equal(t, "Body", string(body))
+
+	meta, body, err = splitFrontMatter([]byte("No front matter\n---\n"))
+	require.NoError(t, err)
+	assert.Nil(t, meta)
+	assert.Equal(t, "No front matter\n---\n", string(body))
+
+	_, _, err = splitFrontMatter([]byte("---\ntitle: [unclosed\n---\nBody"))
+	assert.Error(t, err)
+}
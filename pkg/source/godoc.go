@@ -0,0 +1,431 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/doc"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/xhad/yes/internal/glob"
+	"github.com/xhad/yes/internal/models"
+)
+
+// Symbol kinds recorded in the "kind" metadata of Go documents.
+const (
+	KindPackage = "package"
+	KindConst   = "const"
+	KindVar     = "var"
+	KindType    = "type"
+	KindFunc    = "func"
+	KindMethod  = "method"
+)
+
+// GoPackagesConfig configures a GoPackages source.
+type GoPackagesConfig struct {
+	Root              string
+	Exclude           []string // globs for directories to skip
+	IncludeUnexported bool
+	OnProgress        func(importPath string)
+}
+
+// GoPackages parses the Go packages below a directory with go/parser and
+// go/doc. Every package, constant and variable group, type, function and
+// method becomes a document holding its signature and doc comment, with the
+// import path, symbol kind and file:line recorded in the metadata.
+type GoPackages struct {
+	config  GoPackagesConfig
+	root    string
+	exclude []*glob.Pattern
+	modules map[string]string // module directory -> module path
+}
+
+func NewGoPackagesWithConfig(config GoPackagesConfig) (*GoPackages, error) {
+	if config.Root == "" {
+		config.Root = "."
+	}
+
+	root, err := filepath.Abs(config.Root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+
+	exclude, err := glob.CompileAll(config.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &GoPackages{
+		config:  config,
+		root:    root,
+		exclude: exclude,
+		modules: make(map[string]string),
+	}
+
+	// The root may sit inside a module rather than at its top.
+	for dir := root; ; dir = filepath.Dir(dir) {
+		if modulePath := readModulePath(dir); modulePath != "" {
+			g.modules[dir] = modulePath
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+
+	return g, nil
+}
+
+// Load parses every package under the root.
+func (g *GoPackages) Load(ctx context.Context) ([]models.Document, error) {
+	return collect(g.Stream(ctx))
+}
+
+// Stream parses the packages under the root in the background and sends
+// their documents on the returned channel. Packages that fail to parse are
+// logged and skipped. Once the document channel is closed the error channel
+// yields the result of the walk.
+func (g *GoPackages) Stream(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(docs)
+
+		errc <- filepath.WalkDir(g.root, func(dir string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if !entry.IsDir() {
+				return nil
+			}
+
+			relDir, err := filepath.Rel(g.root, dir)
+			if err != nil {
+				return err
+			}
+			relDir = filepath.ToSlash(relDir)
+
+			if relDir != "." {
+				name := entry.Name()
+				// The go command ignores these directories as well.
+				if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+					return filepath.SkipDir
+				}
+				if glob.MatchAny(g.exclude, relDir) {
+					return filepath.SkipDir
+				}
+				if modulePath := readModulePath(dir); modulePath != "" {
+					g.modules[dir] = modulePath
+				}
+			}
+
+			pkgDocs, err := g.loadPackage(dir)
+			if err != nil {
+				log.Printf("Error parsing package %s: %v", relDir, err)
+				return nil
+			}
+
+			for _, doc := range pkgDocs {
+				select {
+				case docs <- doc:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}()
+
+	return docs, errc
+}
+
+// loadPackage parses the package in dir. Directories without Go files yield
+// no documents.
+func (g *GoPackages) loadPackage(dir string) ([]models.Document, error) {
+	buildPkg, err := build.Default.ImportDir(dir, 0)
+	if err != nil {
+		var noGo *build.NoGoError
+		if errors.As(err, &noGo) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	importPath := g.importPath(dir)
+	if g.config.OnProgress != nil {
+		g.config.OnProgress(importPath)
+	}
+
+	fset := token.NewFileSet()
+	var files []*ast.File
+	for _, name := range buildPkg.GoFiles {
+		file, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	var mode doc.Mode
+	if g.config.IncludeUnexported {
+		mode = doc.AllDecls
+	}
+	pkg, err := doc.NewFromFiles(fset, files, importPath, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &goDocRenderer{root: g.root, fset: fset, pkg: pkg, files: files, dir: dir}
+	return r.documents(), nil
+}
+
+// importPath derives the import path of dir from the nearest enclosing
+// module. Outside of a module the path relative to the root is used.
+func (g *GoPackages) importPath(dir string) string {
+	for moduleDir := dir; ; moduleDir = filepath.Dir(moduleDir) {
+		if modulePath, ok := g.modules[moduleDir]; ok {
+			rel, err := filepath.Rel(moduleDir, dir)
+			if err != nil || rel == "." {
+				return modulePath
+			}
+			return path.Join(modulePath, filepath.ToSlash(rel))
+		}
+		if filepath.Dir(moduleDir) == moduleDir {
+			break
+		}
+	}
+
+	rel, err := filepath.Rel(g.root, dir)
+	if err != nil || rel == "." {
+		return filepath.Base(dir)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// readModulePath returns the module path declared in dir/go.mod.
+func readModulePath(dir string) string {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if modulePath, ok := strings.CutPrefix(line, "module"); ok && modulePath != line {
+			modulePath = strings.TrimSpace(modulePath)
+			if i := strings.Index(modulePath, "//"); i >= 0 {
+				modulePath = strings.TrimSpace(modulePath[:i])
+			}
+			return strings.Trim(modulePath, `"`)
+		}
+	}
+	return ""
+}
+
+// goDocRenderer turns the doc.Package of one directory into documents.
+type goDocRenderer struct {
+	root  string
+	fset  *token.FileSet
+	pkg   *doc.Package
+	files []*ast.File
+	dir   string
+}
+
+func (r *goDocRenderer) documents() []models.Document {
+	pkg := r.pkg
+	docs := []models.Document{r.packageDocument()}
+
+	for _, value := range pkg.Consts {
+		docs = append(docs, r.valueDocument(KindConst, value))
+	}
+	for _, value := range pkg.Vars {
+		docs = append(docs, r.valueDocument(KindVar, value))
+	}
+	for _, fn := range pkg.Funcs {
+		docs = append(docs, r.funcDocument(fn))
+	}
+	for _, typ := range pkg.Types {
+		docs = append(docs, r.typeDocument(typ))
+		for _, value := range typ.Consts {
+			docs = append(docs, r.valueDocument(KindConst, value))
+		}
+		for _, value := range typ.Vars {
+			docs = append(docs, r.valueDocument(KindVar, value))
+		}
+		for _, fn := range typ.Funcs {
+			docs = append(docs, r.funcDocument(fn))
+		}
+		for _, method := range typ.Methods {
+			docs = append(docs, r.funcDocument(method))
+		}
+	}
+
+	return docs
+}
+
+func (r *goDocRenderer) packageDocument() models.Document {
+	pkg := r.pkg
+	clause := fmt.Sprintf("package %s // import %q", pkg.Name, pkg.ImportPath)
+
+	var pos token.Pos
+	for _, file := range r.files {
+		if file.Doc != nil {
+			pos = file.Package
+			break
+		}
+	}
+	if pos == token.NoPos && len(r.files) > 0 {
+		pos = r.files[0].Package
+	}
+
+	return r.document(KindPackage, "", "", "package "+pkg.Name, clause, pkg.Doc, pos)
+}
+
+func (r *goDocRenderer) valueDocument(kind string, value *doc.Value) models.Document {
+	return r.document(kind, strings.Join(value.Names, ", "), "", value.Names[0], r.source(value.Decl), value.Doc, value.Decl.Pos())
+}
+
+func (r *goDocRenderer) typeDocument(typ *doc.Type) models.Document {
+	text := typ.Doc
+	if members := memberDocs(typ.Decl); members != "" {
+		text = strings.TrimSpace(text + "\n\n" + members)
+	}
+	return r.document(KindType, typ.Name, "", typ.Name, r.source(typ.Decl), text, typ.Decl.Pos())
+}
+
+func (r *goDocRenderer) funcDocument(fn *doc.Func) models.Document {
+	decl := *fn.Decl
+	decl.Body = nil
+
+	kind, name := KindFunc, fn.Name
+	if fn.Recv != "" {
+		kind = KindMethod
+		name = strings.TrimPrefix(fn.Recv, "*") + "." + fn.Name
+	}
+	return r.document(kind, fn.Name, fn.Recv, name, r.source(&decl), fn.Doc, fn.Decl.Pos())
+}
+
+// document assembles a document for one symbol. name is the qualified
+// symbol used for the title and URL fragment, like "Type.Method".
+func (r *goDocRenderer) document(kind, symbol, recv, name, signature, text string, pos token.Pos) models.Document {
+	pkg := r.pkg
+	position := r.fset.Position(pos)
+
+	file := position.Filename
+	if rel, err := filepath.Rel(r.root, file); err == nil {
+		file = filepath.ToSlash(rel)
+	}
+
+	fileURL := &url.URL{Scheme: "file", Path: filepath.ToSlash(position.Filename)}
+	title := pkg.Name + "." + name
+	if kind == KindPackage {
+		fileURL.Path = filepath.ToSlash(r.dir)
+		title = name
+	} else {
+		fileURL.Fragment = name
+	}
+
+	var content, markdown strings.Builder
+	fmt.Fprintf(&content, "%s\n\n%s", title, signature)
+	fmt.Fprintf(&markdown, "## %s\n\n```go\n%s\n```", title, signature)
+	if text = strings.TrimSpace(text); text != "" {
+		comment := pkg.Parser().Parse(text)
+		content.WriteString("\n\n")
+		content.Write(pkg.Printer().Text(comment))
+		markdown.WriteString("\n\n")
+		markdown.Write(pkg.Printer().Markdown(comment))
+	}
+
+	metadata := map[string]interface{}{
+		"source":     "go",
+		"importPath": pkg.ImportPath,
+		"package":    pkg.Name,
+		"kind":       kind,
+		"file":       file,
+		"line":       position.Line,
+		"position":   fmt.Sprintf("%s:%d", file, position.Line),
+	}
+	if symbol != "" {
+		metadata["symbol"] = symbol
+	}
+	if recv != "" {
+		metadata["receiver"] = recv
+	}
+
+	return models.Document{
+		ID:       documentID(fileURL.String()),
+		URL:      fileURL.String(),
+		Title:    title,
+		Content:  strings.TrimSpace(content.String()),
+		Markdown: strings.TrimSpace(markdown.String()),
+		Metadata: metadata,
+	}
+}
+
+// source prints a declaration without its doc comment or function body.
+func (r *goDocRenderer) source(node ast.Node) string {
+	var buf bytes.Buffer
+	config := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := config.Fprint(&buf, r.fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// memberDocs lists the documented fields of struct types and methods of
+// interface types in decl, which the printed signature leaves out.
+func memberDocs(decl *ast.GenDecl) string {
+	var lines []string
+	for _, spec := range decl.Specs {
+		typeSpec, ok := spec.(*ast.TypeSpec)
+		if !ok {
+			continue
+		}
+
+		var fields *ast.FieldList
+		switch t := typeSpec.Type.(type) {
+		case *ast.StructType:
+			fields = t.Fields
+		case *ast.InterfaceType:
+			fields = t.Methods
+		}
+		if fields == nil {
+			continue
+		}
+
+		for _, field := range fields.List {
+			text := strings.TrimSpace(field.Doc.Text() + " " + field.Comment.Text())
+			if text == "" || len(field.Names) == 0 {
+				continue
+			}
+			var names []string
+			for _, name := range field.Names {
+				names = append(names, name.Name)
+			}
+			lines = append(lines, fmt.Sprintf("- %s: %s", strings.Join(names, ", "), strings.Join(strings.Fields(text), " ")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,137 @@
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/internal/models"
+)
+
+const widgetSource = `// Package widget builds widgets.
+package widget
+
+// Size is the size of a widget.
+type Size int
+
+// Sizes a widget can have.
+const (
+	Small Size = iota
+	Large
+)
+
+// Widget is a thing.
+type Widget struct {
+	// Name identifies the widget.
+	Name string
+	size Size
+}
+
+// New returns a widget called name.
+func New(name string) *Widget {
+	return &Widget{Name: name}
+}
+
+// Grow makes the widget [Large].
+func (w *Widget) Grow() {
+	w.size = Large
+}
+
+func (w *Widget) shrink() {}
+`
+
+func loadGoSymbols(t *testing.T, config GoPackagesConfig) map[string]models.Document {
+	t.Helper()
+	g, err := NewGoPackagesWithConfig(config)
+	require.NoError(t, err)
+
+	docs, err := g.Load(context.Background())
+	require.NoError(t, err)
+
+	bySymbol := make(map[string]models.Document)
+	for _, doc := range docs {
+		bySymbol[doc.Title] = doc
+	}
+	return bySymbol
+}
+
+func TestGoPackagesLoad(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":                    "module example.com/shop // comment\n\ngo 1.22\n",
+		"widget/widget.go":          widgetSource,
+		"widget/widget_test.go":     "package widget\n\n// TestOnly is not documented.\nfunc TestOnly() {}\n",
+		"widget/testdata/skip.go":   "package skip\n\nfunc Skipped() {}\n",
+		"internal/util/util.go":     "package util\n\n// Var is shared.\nvar Var = 1\n",
+		"internal/util/README.md":   "# not Go",
+		"cmd/shop/main.go":          "package main\n\nfunc main() {}\n",
+		"nested/go.mod":             "module example.com/nested\n",
+		"nested/inner/inner.go":     "package inner\n\n// Hello greets.\nfunc Hello() string { return \"hi\" }\n",
+		"excluded/generated/gen.go": "package generated\n\nfunc Gen() {}\n",
+	})
+
+	docs := loadGoSymbols(t, GoPackagesConfig{Root: root, Exclude: []string{"excluded/**"}})
+
+	var titles []string
+	for title := range docs {
+		titles = append(titles, title)
+	}
+	assert.ElementsMatch(t, []string{
+		"package widget", "widget.Size", "widget.Small", "widget.Widget",
+		"widget.New", "widget.Widget.Grow",
+		"package util", "util.Var",
+		"package main",
+		"package inner", "inner.Hello",
+	}, titles)
+
+	pkg := docs["package widget"]
+	assert.Equal(t, "example.com/shop/widget", pkg.Metadata["importPath"])
+	assert.Equal(t, KindPackage, pkg.Metadata["kind"])
+	assert.Contains(t, pkg.Content, "Package widget builds widgets.")
+	assert.Equal(t, "widget/widget.go:2", pkg.Metadata["position"])
+
+	grow := docs["widget.Widget.Grow"]
+	assert.Equal(t, KindMethod, grow.Metadata["kind"])
+	assert.Equal(t, "Grow", grow.Metadata["symbol"])
+	assert.Equal(t, "*Widget", grow.Metadata["receiver"])
+	assert.Equal(t, "widget/widget.go", grow.Metadata["file"])
+	assert.Equal(t, 26, grow.Metadata["line"])
+	assert.Contains(t, grow.Content, "func (w *Widget) Grow()")
+	assert.Contains(t, grow.Content, "Grow makes the widget Large.")
+	assert.NotContains(t, grow.Content, "w.size = Large", "function bodies are left out")
+	assert.Contains(t, grow.Markdown, "```go\nfunc (w *Widget) Grow()\n```")
+	assert.Contains(t, grow.URL, "widget.go#Widget.Grow")
+
+	widget := docs["widget.Widget"]
+	assert.Equal(t, KindType, widget.Metadata["kind"])
+	assert.Contains(t, widget.Content, "- Name: Name identifies the widget.")
+	assert.NotContains(t, widget.Content, "size Size")
+
+	small := docs["widget.Small"]
+	assert.Equal(t, KindConst, small.Metadata["kind"])
+	assert.Equal(t, "Small, Large", small.Metadata["symbol"])
+
+	assert.Equal(t, KindFunc, docs["widget.New"].Metadata["kind"])
+	assert.Equal(t, "example.com/shop/internal/util", docs["util.Var"].Metadata["importPath"])
+	assert.Equal(t, "example.com/nested/inner", docs["inner.Hello"].Metadata["importPath"])
+}
+
+func TestGoPackagesIncludeUnexported(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"go.mod":    "module example.com/shop\n",
+		"widget.go": widgetSource,
+	})
+
+	docs := loadGoSymbols(t, GoPackagesConfig{Root: root, IncludeUnexported: true})
+	assert.Contains(t, docs, "widget.Widget.shrink")
+	assert.Equal(t, "example.com/shop", docs["package widget"].Metadata["importPath"])
+}
+
+func TestReadModulePath(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"go.mod": "// header\nmodule \"example.com/quoted\"\n"})
+	assert.Equal(t, "example.com/quoted", readModulePath(root))
+	assert.Equal(t, "", readModulePath(t.TempDir()))
+}
@@ -0,0 +1,109 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/xhad/yes/internal/models"
+	"github.com/xhad/yes/pkg/scraper"
+	"github.com/xhad/yes/pkg/warc"
+)
+
+// WARCConfig configures a WARC source. The max depth recorded for each crawl
+// in the archive replaces Scraper.MaxDepth.
+type WARCConfig struct {
+	Path       string
+	StartURLs  []string              // crawls to replay, the ones recorded in the archive if empty
+	Scraper    scraper.ScraperConfig // crawl settings, which should match the recorded crawl
+	OnProgress func(url string)
+}
+
+// WARC replays crawls recorded in a WARC archive by the scraper. The pages
+// are served from the archive instead of the network, so the same settings
+// produce the same documents as the recorded crawl, offline and repeatably.
+type WARC struct {
+	config WARCConfig
+	replay *warc.Replay
+}
+
+func NewWARCWithConfig(config WARCConfig) (*WARC, error) {
+	replay, err := warc.OpenReplay(config.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.StartURLs) == 0 {
+		config.StartURLs = replay.StartURLs()
+	}
+	if len(config.StartURLs) == 0 {
+		return nil, fmt.Errorf("no recorded crawls in %s", config.Path)
+	}
+
+	// Nothing is fetched from the sites, so there is nothing to rate limit
+	if config.Scraper.RateLimit == 0 {
+		config.Scraper.RateLimit = math.MaxFloat64
+	}
+	config.Scraper.Transport = replay
+	config.Scraper.OnProgress = config.OnProgress
+	config.Scraper.Archive = nil
+	config.Scraper.PageCache = nil
+	config.Scraper.Checkpoint = nil
+	config.Scraper.Resume = false
+
+	return &WARC{config: config, replay: replay}, nil
+}
+
+// Load replays every crawl and returns the documents.
+func (w *WARC) Load(ctx context.Context) ([]models.Document, error) {
+	return collect(w.Stream(ctx))
+}
+
+// Stream replays the crawls in the background and sends each document on
+// the returned channel as soon as it is extracted. Once the document channel
+// is closed the error channel yields the result of the replay.
+func (w *WARC) Stream(ctx context.Context) (<-chan models.Document, <-chan error) {
+	docs := make(chan models.Document)
+	errc := make(chan error, 1)
+
+	go func() {
+		defer close(errc)
+		defer close(docs)
+
+		var errs []error
+		for _, startURL := range w.config.StartURLs {
+			config := w.config.Scraper
+			if config.BaseURL == "" {
+				config.BaseURL = startURL
+			}
+			// Replaying deeper than the recording only finds missing pages,
+			// and shallower leaves recorded ones out
+			if depth, ok := w.replay.MaxDepth(startURL); ok {
+				config.MaxDepth = depth
+			}
+			s, err := scraper.NewWithConfig(config)
+			if err != nil {
+				errc <- err
+				return
+			}
+
+			pages, crawlErrc := s.ScrapeStream(ctx, startURL)
+			for doc := range pages {
+				select {
+				case docs <- doc:
+				case <-ctx.Done():
+				}
+			}
+			if err := <-crawlErrc; err != nil {
+				if ctx.Err() != nil {
+					errc <- ctx.Err()
+					return
+				}
+				errs = append(errs, fmt.Errorf("failed to replay %s: %v", startURL, err))
+			}
+		}
+		errc <- errors.Join(errs...)
+	}()
+
+	return docs, errc
+}
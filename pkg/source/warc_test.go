@@ -0,0 +1,70 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xhad/yes/pkg/scraper"
+	"github.com/xhad/yes/pkg/warc"
+)
+
+func TestWARCReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			fmt.Fprint(w, `<html><head><title>Home</title></head><body><main><p>Home</p><a href="/guide">guide</a></main></body></html>`)
+		case "/guide":
+			fmt.Fprint(w, `<html><head><title>Guide</title></head><body><main><p>Guide</p></main></body></html>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	path := filepath.Join(t.TempDir(), "crawl.warc.gz")
+	archive, err := warc.Create(path, scraper.DefaultUserAgent)
+	require.NoError(t, err)
+	s, err := scraper.NewWithConfig(scraper.ScraperConfig{
+		BaseURL:   server.URL,
+		RateLimit: 1000,
+		Archive:   archive,
+	})
+	require.NoError(t, err)
+	_, err = s.Scrape(server.URL + "/")
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+	server.Close()
+
+	var progress int32
+	src, err := NewWARCWithConfig(WARCConfig{
+		Path:       path,
+		OnProgress: func(string) { atomic.AddInt32(&progress, 1) },
+	})
+	require.NoError(t, err)
+
+	docs, err := src.Load(context.Background())
+	require.NoError(t, err)
+
+	titles := make(map[string]string)
+	for _, doc := range docs {
+		titles[doc.URL] = doc.Title
+	}
+	assert.Equal(t, map[string]string{server.URL + "/": "Home", server.URL + "/guide": "Guide"}, titles)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&progress))
+}
+
+func TestWARCWithoutCrawls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.warc")
+	archive, err := warc.Create(path, "test")
+	require.NoError(t, err)
+	require.NoError(t, archive.Close())
+
+	_, err = NewWARCWithConfig(WARCConfig{Path: path})
+	assert.ErrorContains(t, err, "no recorded crawls")
+}
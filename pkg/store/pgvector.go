@@ -2,10 +2,12 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"unicode/utf8"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pgvector/pgvector-go"
 	"github.com/xhad/yes/internal/models"
@@ -79,7 +81,9 @@ func (vs *VectorStore) initialize() error {
 			content TEXT,
 			chunk_index INTEGER,
 			embedding vector(%d),
-			metadata JSONB
+			metadata JSONB,
+			normalized TEXT,
+			links JSONB
 		)`, vs.config.TableName, vs.config.VectorDim)
 
 	_, err = vs.pool.Exec(ctx, createTable)
@@ -87,6 +91,18 @@ func (vs *VectorStore) initialize() error {
 		return fmt.Errorf("failed to create table: %v", err)
 	}
 
+	// Add the columns of newer versions to tables created without them
+	addNormalized := fmt.Sprintf(`
+		ALTER TABLE %s
+		ADD COLUMN IF NOT EXISTS normalized TEXT,
+		ADD COLUMN IF NOT EXISTS links JSONB`,
+		vs.config.TableName)
+
+	_, err = vs.pool.Exec(ctx, addNormalized)
+	if err != nil {
+		return fmt.Errorf("failed to add columns: %v", err)
+	}
+
 	// Create vector index
 	createIndex := fmt.Sprintf(`
 		CREATE INDEX IF NOT EXISTS %s_embedding_idx 
@@ -100,9 +116,55 @@ func (vs *VectorStore) initialize() error {
 		return fmt.Errorf("failed to create index: %v", err)
 	}
 
+	// Create URL index used for page lookups during re-crawls
+	createURLIndex := fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s_url_idx ON %s (url)`,
+		vs.config.TableName, vs.config.TableName)
+
+	_, err = vs.pool.Exec(ctx, createURLIndex)
+	if err != nil {
+		return fmt.Errorf("failed to create url index: %v", err)
+	}
+
+	// Create metadata index used to filter documents by page metadata
+	createMetadataIndex := fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s_metadata_idx ON %s USING GIN (metadata jsonb_path_ops)`,
+		vs.config.TableName, vs.config.TableName)
+
+	_, err = vs.pool.Exec(ctx, createMetadataIndex)
+	if err != nil {
+		return fmt.Errorf("failed to create metadata index: %v", err)
+	}
+
+	// Create full-text index used by KeywordQuery on normalized text
+	createKeywordIndex := fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s_normalized_idx ON %s USING GIN (to_tsvector('simple', COALESCE(normalized, '')))`,
+		vs.config.TableName, vs.config.TableName)
+
+	_, err = vs.pool.Exec(ctx, createKeywordIndex)
+	if err != nil {
+		return fmt.Errorf("failed to create keyword index: %v", err)
+	}
+
+	// Create the table holding crawl checkpoints
+	createCheckpoints := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s_checkpoints (
+			start_url TEXT PRIMARY KEY,
+			state JSONB NOT NULL,
+			updated_at TIMESTAMPTZ NOT NULL
+		)`, vs.config.TableName)
+
+	_, err = vs.pool.Exec(ctx, createCheckpoints)
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint table: %v", err)
+	}
+
 	return nil
 }
 
+// Store writes the chunks of docs, replacing what was stored for their URLs
+// before. Every document of a URL, such as the pages of a PDF file, must be
+// stored in the same call.
 func (vs *VectorStore) Store(docs []models.ProcessedDocument) error {
 	ctx := context.Background()
 
@@ -115,27 +177,70 @@ func (vs *VectorStore) Store(docs []models.ProcessedDocument) error {
 
 	// Prepare the insert statement
 	stmt := fmt.Sprintf(`
-		INSERT INTO %s (id, url, title, content, chunk_index, embedding, metadata)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO %s (id, url, title, content, chunk_index, embedding, metadata, normalized, links)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		ON CONFLICT (id) DO UPDATE SET
 			content = EXCLUDED.content,
 			embedding = EXCLUDED.embedding,
-			metadata = EXCLUDED.metadata`,
+			metadata = EXCLUDED.metadata,
+			normalized = EXCLUDED.normalized,
+			links = EXCLUDED.links`,
 		vs.config.TableName)
 
+	// Remove chunks left over from a previous version of each page, once
+	// per URL. The pages of a PDF file are separate documents sharing its
+	// URL and are stored together, so chunks of a page the file no longer
+	// has go too.
+	deleteStmt := fmt.Sprintf(`DELETE FROM %s WHERE url = $1 AND NOT (id = ANY($2))`, vs.config.TableName)
+
+	var urls []string
+	chunkIDs := make(map[string][]string)
+	for _, doc := range docs {
+		if _, ok := chunkIDs[doc.URL]; !ok {
+			urls = append(urls, doc.URL)
+			chunkIDs[doc.URL] = []string{}
+		}
+		for i := range doc.Chunks {
+			chunkIDs[doc.URL] = append(chunkIDs[doc.URL], fmt.Sprintf("%s_%d", doc.ID, i))
+		}
+	}
+	for _, url := range urls {
+		if _, err := tx.Exec(ctx, deleteStmt, url, chunkIDs[url]); err != nil {
+			return fmt.Errorf("failed to delete previous chunks: %v", err)
+		}
+	}
+
 	emb := llm.NewEmbedder()
 
 	// Insert documents in batches
 	for _, doc := range docs {
-
 		cleanTitle := sanitizeUTF8(doc.Title)
 
 		for i, chunk := range doc.Chunks {
 			cleanChunk := sanitizeUTF8(chunk)
 			id := fmt.Sprintf("%s_%d", doc.ID, i)
 
+			// Embed the normalized chunk along with the headings it is under
+			var headingPath string
+			if i < len(doc.HeadingPaths) {
+				headingPath = sanitizeUTF8(doc.HeadingPaths[i])
+			}
+			normalized := cleanChunk
+			if i < len(doc.Normalized) {
+				normalized = sanitizeUTF8(doc.Normalized[i])
+			}
+			var code models.ChunkCode
+			if i < len(doc.Code) {
+				code = doc.Code[i]
+			}
+
+			// The links of a page are only needed once, by CachedPage
+			var links interface{}
+			if i == 0 && doc.Links != nil {
+				links = doc.Links
+			}
 			reChunk := make([]string, 1)
-			reChunk[0] = cleanChunk // Replace 'chunk1' with your first chunk data
+			reChunk[0] = models.EmbeddingText(headingPath, normalized)
 
 			embedding, err := emb.Embed.CreateEmbedding(ctx, reChunk)
 
@@ -161,7 +266,9 @@ func (vs *VectorStore) Store(docs []models.ProcessedDocument) error {
 				cleanChunk,
 				i,
 				vectorEmbeddings,
-				doc.Metadata,
+				chunkMetadata(doc.Metadata, headingPath, code),
+				normalized,
+				links,
 			)
 			if err != nil {
 				return fmt.Errorf("failed to insert document: %v", err)
@@ -218,6 +325,115 @@ func (vs *VectorStore) Query(queryEmbedding []float32, limit int) ([]models.Docu
 	return docs, nil
 }
 
+// KeywordQuery returns the chunks whose normalized text holds every word of
+// query, best matches first. The query should be normalized the same way as
+// the chunks, with processor.Processor.Normalize, so that words like removed
+// stopwords do not rule out every chunk.
+func (vs *VectorStore) KeywordQuery(query string, limit int) ([]models.Document, error) {
+	ctx := context.Background()
+
+	if limit == 0 {
+		limit = vs.config.SearchLimit
+	}
+
+	// The expression matches the keyword index
+	keywordQuery := fmt.Sprintf(`
+		SELECT id, url, title, content, metadata
+		FROM %s
+		WHERE to_tsvector('simple', COALESCE(normalized, '')) @@ plainto_tsquery('simple', $1)
+		ORDER BY ts_rank(to_tsvector('simple', COALESCE(normalized, '')), plainto_tsquery('simple', $1)) DESC
+		LIMIT $2`,
+		vs.config.TableName)
+
+	rows, err := vs.pool.Query(ctx, keywordQuery, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query documents by keyword: %v", err)
+	}
+	defer rows.Close()
+
+	var docs []models.Document
+	for rows.Next() {
+		var doc models.Document
+		err := rows.Scan(
+			&doc.ID,
+			&doc.URL,
+			&doc.Title,
+			&doc.Content,
+			&doc.Metadata,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan row: %v", err)
+		}
+		docs = append(docs, doc)
+	}
+
+	return docs, nil
+}
+
+// CachedPage returns the HTTP validators and outgoing links stored with the
+// first chunk of url, or of the page url redirected to or named as canonical.
+// It implements scraper.PageCache. Pages stored before links had a column of
+// their own have them in their metadata.
+func (vs *VectorStore) CachedPage(ctx context.Context, url string) (models.CachedPage, bool, error) {
+	query := fmt.Sprintf(`
+		SELECT
+			COALESCE(metadata->>'etag', ''),
+			COALESCE(metadata->>'lastModified', ''),
+			COALESCE(links, metadata->'links', '[]'::jsonb)
+		FROM %s
+		WHERE url = $1 OR metadata @> jsonb_build_object('requestURL', $1::text)
+		ORDER BY url = $1 DESC, chunk_index
+		LIMIT 1`,
+		vs.config.TableName)
+
+	var page models.CachedPage
+	err := vs.pool.QueryRow(ctx, query, url).Scan(&page.ETag, &page.LastModified, &page.Links)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.CachedPage{}, false, nil
+	}
+	if err != nil {
+		return models.CachedPage{}, false, fmt.Errorf("failed to query cached page: %v", err)
+	}
+
+	return page, true, nil
+}
+
+// LoadCheckpoint returns the saved state of the crawl started from startURL.
+// It implements scraper.CheckpointStore.
+func (vs *VectorStore) LoadCheckpoint(ctx context.Context, startURL string) (models.CrawlCheckpoint, bool, error) {
+	query := fmt.Sprintf(`SELECT state FROM %s_checkpoints WHERE start_url = $1`, vs.config.TableName)
+
+	var checkpoint models.CrawlCheckpoint
+	err := vs.pool.QueryRow(ctx, query, startURL).Scan(&checkpoint)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.CrawlCheckpoint{}, false, nil
+	}
+	if err != nil {
+		return models.CrawlCheckpoint{}, false, fmt.Errorf("failed to query checkpoint: %v", err)
+	}
+
+	return checkpoint, true, nil
+}
+
+// SaveCheckpoint replaces the saved state of a crawl. It implements
+// scraper.CheckpointStore.
+func (vs *VectorStore) SaveCheckpoint(ctx context.Context, checkpoint models.CrawlCheckpoint) error {
+	stmt := fmt.Sprintf(`
+		INSERT INTO %s_checkpoints (start_url, state, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (start_url) DO UPDATE SET
+			state = EXCLUDED.state,
+			updated_at = EXCLUDED.updated_at`,
+		vs.config.TableName)
+
+	_, err := vs.pool.Exec(ctx, stmt, checkpoint.StartURL, checkpoint, checkpoint.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %v", err)
+	}
+
+	return nil
+}
+
 func (vs *VectorStore) Close() {
 	if vs.pool != nil {
 		vs.pool.Close()
@@ -241,3 +457,28 @@ func sanitizeUTF8(s string) string {
 	}
 	return s
 }
+
+// chunkMetadata returns the metadata stored with a chunk: its document's,
+// with the chunk's heading path and code blocks added when it has them.
+func chunkMetadata(metadata map[string]interface{}, headingPath string, code models.ChunkCode) map[string]interface{} {
+	if headingPath == "" && code.Blocks == 0 {
+		return metadata
+	}
+	chunk := make(map[string]interface{}, len(metadata)+4)
+	for key, value := range metadata {
+		chunk[key] = value
+	}
+	if headingPath != "" {
+		chunk["headingPath"] = headingPath
+	}
+	if code.Blocks > 0 {
+		chunk["codeBlocks"] = code.Blocks
+		if len(code.Languages) > 0 {
+			chunk["languages"] = code.Languages
+		}
+		if code.Context != "" {
+			chunk["codeContext"] = sanitizeUTF8(code.Context)
+		}
+	}
+	return chunk
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -82,3 +83,146 @@ func TestVectorStore(t *testing.T) {
 	assert.Equal(t, docs[0].URL, results[0].URL)
 	assert.Equal(t, docs[0].Title, results[0].Title)
 }
+
+func TestVectorStoreKeywordQuery(t *testing.T) {
+	s, err := store.NewWithConfig(getTestConfig())
+	require.NoError(t, err)
+	defer s.Close()
+
+	docs := []models.ProcessedDocument{
+		{
+			Document: models.Document{
+				ID:    "keyword1",
+				URL:   "https://example.com/keyword",
+				Title: "Keyword Document",
+			},
+			Chunks:     []string{"Call NewClient() with your API key.", "Tokens expire after an hour."},
+			Normalized: []string{"call newclient with your api key", "tokens expire after an hour"},
+		},
+	}
+	require.NoError(t, s.Store(docs))
+
+	// Matching is on the normalized text, but the original is returned
+	results, err := s.KeywordQuery("newclient api key", 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, "keyword1_0", results[0].ID)
+	assert.Equal(t, "Call NewClient() with your API key.", results[0].Content)
+
+	results, err = s.KeywordQuery("newclient expire", 5)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestVectorStoreReplacesPages(t *testing.T) {
+	s, err := store.NewWithConfig(getTestConfig())
+	require.NoError(t, err)
+	defer s.Close()
+
+	page := func(id, text string) models.ProcessedDocument {
+		return models.ProcessedDocument{
+			Document:   models.Document{ID: id, URL: "https://example.com/manual.pdf", Title: "Manual"},
+			Chunks:     []string{text},
+			Normalized: []string{text},
+		}
+	}
+	require.NoError(t, s.Store([]models.ProcessedDocument{page("manual1", "installing widgets"), page("manual2", "calibrating gizmos")}))
+
+	// A new version of the file with fewer pages drops the chunks of the rest
+	require.NoError(t, s.Store([]models.ProcessedDocument{page("manual1", "installing widgets again")}))
+
+	results, err := s.KeywordQuery("calibrating gizmos", 5)
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	results, err = s.KeywordQuery("installing widgets", 5)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "installing widgets again", results[0].Content)
+}
+
+func TestVectorStoreCachedPage(t *testing.T) {
+	config := getTestConfig()
+	s, err := store.NewWithConfig(config)
+	require.NoError(t, err)
+	defer s.Close()
+
+	docs := []models.ProcessedDocument{
+		{
+			Document: models.Document{
+				ID:    "cached1",
+				URL:   "https://example.com/cached",
+				Title: "Cached Document",
+				Metadata: map[string]interface{}{
+					"etag":         `"abc"`,
+					"lastModified": "Mon, 01 Jan 2024 00:00:00 GMT",
+				},
+				Links: []string{"https://example.com/next"},
+			},
+			Chunks: []string{"This is a cached chunk"},
+		},
+	}
+	require.NoError(t, s.Store(docs))
+
+	page, ok, err := s.CachedPage(context.Background(), "https://example.com/cached")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, `"abc"`, page.ETag)
+	assert.Equal(t, "Mon, 01 Jan 2024 00:00:00 GMT", page.LastModified)
+	assert.Equal(t, []string{"https://example.com/next"}, page.Links)
+
+	// Pages reached through a redirect are found by the URL requested
+	redirected := []models.ProcessedDocument{
+		{
+			Document: models.Document{
+				ID:  "cached2",
+				URL: "https://example.com/moved",
+				Metadata: map[string]interface{}{
+					"etag":       `"def"`,
+					"requestURL": "https://example.com/old",
+				},
+			},
+			Chunks: []string{"This is a moved chunk"},
+		},
+	}
+	require.NoError(t, s.Store(redirected))
+
+	page, ok, err = s.CachedPage(context.Background(), "https://example.com/old")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, `"def"`, page.ETag)
+
+	_, ok, err = s.CachedPage(context.Background(), "https://example.com/missing")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestCheckpoints(t *testing.T) {
+	s, err := store.NewWithConfig(getTestConfig())
+	require.NoError(t, err)
+	defer s.Close()
+
+	ctx := context.Background()
+	checkpoint := models.CrawlCheckpoint{
+		StartURL:  "https://example.com/",
+		UpdatedAt: time.Now().UTC().Truncate(time.Second),
+		Pages: []models.CrawlPage{
+			{URL: "https://example.com/", Root: true, Status: models.PageStored},
+			{URL: "https://example.com/a", Depth: 1, Status: models.PageQueued},
+		},
+	}
+	require.NoError(t, s.SaveCheckpoint(ctx, checkpoint))
+
+	checkpoint.Complete = true
+	require.NoError(t, s.SaveCheckpoint(ctx, checkpoint))
+
+	loaded, ok, err := s.LoadCheckpoint(ctx, "https://example.com/")
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, loaded.Complete)
+	assert.Equal(t, checkpoint.Pages, loaded.Pages)
+
+	_, ok, err = s.LoadCheckpoint(ctx, "https://example.org/")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
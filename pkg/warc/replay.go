@@ -0,0 +1,168 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FieldCrawlStart names the field of the metadata record the scraper writes
+// at the start of every crawl, whose target URI is the crawl's start URL.
+const FieldCrawlStart = "crawl-start"
+
+// FieldMaxDepth is the field of the crawl start record holding the max depth
+// of the crawl.
+const FieldMaxDepth = "max-depth"
+
+// crawlDelayLine matches Crawl-delay lines of robots.txt files.
+var crawlDelayLine = regexp.MustCompile(`(?im)^\s*crawl-delay\s*:.*$`)
+
+// Replay is an http.RoundTripper answering requests with the responses
+// recorded in a WARC archive, so that a crawl can be repeated offline with
+// exactly the pages it saw. It is safe for concurrent use.
+//
+// When a URL was recorded more than once, as happens with retries, the
+// responses are replayed in the order they were recorded and the last one is
+// repeated. URLs that were never recorded get a 404 Not Found. Crawl-delay
+// lines are dropped from robots.txt files, since a replay puts no load on
+// the site.
+type Replay struct {
+	mu        sync.Mutex
+	responses map[string][][]byte // by target URI
+	served    map[string]int
+	starts    []string
+	depths    map[string]int // recorded max depth, by start URL
+}
+
+// NewReplay returns a replay of the response records among records.
+func NewReplay(records []*Record) *Replay {
+	r := &Replay{
+		responses: make(map[string][][]byte),
+		served:    make(map[string]int),
+		depths:    make(map[string]int),
+	}
+	for _, record := range records {
+		switch record.Type() {
+		case TypeResponse:
+			if strings.HasPrefix(record.Header.Get("Content-Type"), "application/http") {
+				uri := record.TargetURI()
+				r.responses[uri] = append(r.responses[uri], record.Content)
+			}
+		case TypeMetadata:
+			fields := ParseFields(record.Content)
+			if _, ok := fields[FieldCrawlStart]; ok {
+				r.starts = append(r.starts, record.TargetURI())
+				if depth, err := strconv.Atoi(fields[FieldMaxDepth]); err == nil {
+					r.depths[record.TargetURI()] = depth
+				}
+			}
+		}
+	}
+	return r
+}
+
+// OpenReplay reads the WARC file at path into a replay.
+func OpenReplay(path string) (*Replay, error) {
+	records, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewReplay(records), nil
+}
+
+// StartURLs returns the start URLs of the crawls recorded in the archive, in
+// the order they ran.
+func (r *Replay) StartURLs() []string {
+	return r.starts
+}
+
+// MaxDepth returns the max depth recorded at the start of the crawl from
+// startURL, the last one if it was crawled more than once.
+func (r *Replay) MaxDepth(startURL string) (int, bool) {
+	depth, ok := r.depths[startURL]
+	return depth, ok
+}
+
+// Len returns the number of URLs with a recorded response.
+func (r *Replay) Len() int {
+	return len(r.responses)
+}
+
+func (r *Replay) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	uri := req.URL.String()
+
+	r.mu.Lock()
+	recorded := r.responses[uri]
+	var content []byte
+	if len(recorded) > 0 {
+		i := r.served[uri]
+		if i < len(recorded)-1 {
+			r.served[uri] = i + 1
+		}
+		content = recorded[i]
+	}
+	r.mu.Unlock()
+
+	if content == nil {
+		return notArchived(req), nil
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(content)), req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archived response for %s: %v", uri, err)
+	}
+
+	if req.URL.Path == "/robots.txt" {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid archived response for %s: %v", uri, err)
+		}
+		body = crawlDelayLine.ReplaceAll(body, nil)
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		resp.ContentLength = int64(len(body))
+		resp.Header.Del("Content-Length")
+	}
+
+	return resp, nil
+}
+
+// notArchived is the response to a request for a URL that was not recorded.
+func notArchived(req *http.Request) *http.Response {
+	body := "not in archive\n"
+	return &http.Response{
+		Status:        "404 Not Found",
+		StatusCode:    http.StatusNotFound,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain; charset=utf-8"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// ParseFields parses the content of an application/warc-fields record into a
+// map of field names, lower-cased, to values.
+func ParseFields(content []byte) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(string(content), "\n") {
+		name, value, ok := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if !ok {
+			continue
+		}
+		fields[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return fields
+}
@@ -0,0 +1,294 @@
+// Package warc reads and writes Web ARChive files as described in the WARC
+// 1.1 specification (ISO 28500). Files ending in .gz hold one gzip member per
+// record, which is how crawlers conventionally compress them.
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is written at the start of every record.
+const Version = "WARC/1.1"
+
+// Record types.
+const (
+	TypeInfo     = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+	TypeMetadata = "metadata"
+	TypeResource = "resource"
+)
+
+// Content types of HTTP request and response records.
+const (
+	ContentTypeRequest  = "application/http;msgtype=request"
+	ContentTypeResponse = "application/http;msgtype=response"
+	ContentTypeFields   = "application/warc-fields"
+)
+
+// Field is a named header field of a record.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Header holds the fields of a record in the order they were written. Field
+// names are case-insensitive.
+type Header []Field
+
+// Get returns the value of the first field called name, or "".
+func (h Header) Get(name string) string {
+	for _, field := range h {
+		if strings.EqualFold(field.Name, name) {
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of the field called name, or appends the field.
+func (h *Header) Set(name, value string) {
+	for i, field := range *h {
+		if strings.EqualFold(field.Name, name) {
+			(*h)[i].Value = value
+			return
+		}
+	}
+	*h = append(*h, Field{Name: name, Value: value})
+}
+
+// Record is a single WARC record.
+type Record struct {
+	Header  Header
+	Content []byte
+}
+
+// Type returns the WARC-Type of the record.
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// TargetURI returns the WARC-Target-URI of the record.
+func (r *Record) TargetURI() string {
+	return r.Header.Get("WARC-Target-URI")
+}
+
+// NewRecordID returns a new WARC-Record-ID, a random UUID URN.
+func NewRecordID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant 10
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Writer appends records to a WARC file. It is safe for concurrent use.
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	compress bool
+	closer   io.Closer
+}
+
+// NewWriter returns a writer appending records to w, gzip compressing each
+// record when compress is set.
+func NewWriter(w io.Writer, compress bool) *Writer {
+	return &Writer{w: w, compress: compress}
+}
+
+// Create creates the WARC file at path, compressed if path ends in .gz, and
+// writes a warcinfo record naming software.
+func Create(path, software string) (*Writer, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WARC file: %v", err)
+	}
+
+	w := NewWriter(file, strings.HasSuffix(path, ".gz"))
+	w.closer = file
+
+	info := fmt.Sprintf("software: %s\r\nformat: WARC File Format 1.1\r\n", software)
+	err = w.WriteRecord(&Record{
+		Header: Header{
+			{Name: "WARC-Type", Value: TypeInfo},
+			{Name: "WARC-Filename", Value: path},
+			{Name: "Content-Type", Value: ContentTypeFields},
+		},
+		Content: []byte(info),
+	})
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteRecord appends r. WARC-Record-ID, WARC-Date, WARC-Block-Digest and
+// Content-Length are filled in when missing.
+func (w *Writer) WriteRecord(r *Record) error {
+	if r.Header.Get("WARC-Record-ID") == "" {
+		r.Header.Set("WARC-Record-ID", NewRecordID())
+	}
+	if r.Header.Get("WARC-Date") == "" {
+		r.Header.Set("WARC-Date", time.Now().UTC().Format(time.RFC3339))
+	}
+	if r.Header.Get("WARC-Block-Digest") == "" {
+		r.Header.Set("WARC-Block-Digest", digest(r.Content))
+	}
+	r.Header.Set("Content-Length", strconv.Itoa(len(r.Content)))
+
+	var buf bytes.Buffer
+	buf.WriteString(Version + "\r\n")
+	for _, field := range r.Header {
+		fmt.Fprintf(&buf, "%s: %s\r\n", field.Name, field.Value)
+	}
+	buf.WriteString("\r\n")
+	buf.Write(r.Content)
+	buf.WriteString("\r\n\r\n")
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.compress {
+		_, err := w.w.Write(buf.Bytes())
+		return err
+	}
+
+	gz := gzip.NewWriter(w.w)
+	if _, err := gz.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Close closes the file opened by Create. It does nothing for writers made
+// with NewWriter.
+func (w *Writer) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+// digest returns the SHA-1 digest of content in the sha1:BASE32 form used by
+// WARC files.
+func digest(content []byte) string {
+	sum := sha1.Sum(content)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// Reader reads records from a WARC file, compressed or not.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a reader for r. Gzip compression is detected from the
+// data rather than the file name.
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		// gzip.Reader reads concatenated members as a single stream.
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		br = bufio.NewReader(gz)
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record, or io.EOF when there are no more.
+func (r *Reader) Next() (*Record, error) {
+	// Skip the blank lines between records.
+	var line string
+	for {
+		var err error
+		line, err = r.r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			return nil, io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" {
+			break
+		}
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+	}
+
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("invalid WARC record: unexpected %q", line)
+	}
+
+	record := &Record{}
+	for {
+		line, err := r.r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid WARC record header: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid WARC header line %q", line)
+		}
+		record.Header = append(record.Header, Field{Name: name, Value: strings.TrimSpace(value)})
+	}
+
+	length, err := strconv.ParseInt(record.Header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, errors.New("invalid WARC record: missing Content-Length")
+	}
+
+	record.Content = make([]byte, length)
+	if _, err := io.ReadFull(r.r, record.Content); err != nil {
+		return nil, fmt.Errorf("invalid WARC record: %v", err)
+	}
+	return record, nil
+}
+
+// ReadFile reads every record of the WARC file at path.
+func ReadFile(path string) ([]*Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	var records []*Record
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+		records = append(records, record)
+	}
+}
@@ -0,0 +1,116 @@
+package warc
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriterReader(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := NewWriter(&buf, compress)
+		for _, content := range []string{"first", "second\r\n\r\nwith blank lines"} {
+			require.NoError(t, w.WriteRecord(&Record{
+				Header: Header{
+					{Name: "WARC-Type", Value: TypeResource},
+					{Name: "WARC-Target-URI", Value: "https://example.com/" + content[:5]},
+				},
+				Content: []byte(content),
+			}))
+		}
+
+		r, err := NewReader(&buf)
+		require.NoError(t, err)
+
+		first, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, TypeResource, first.Type())
+		assert.Equal(t, "https://example.com/first", first.TargetURI())
+		assert.Equal(t, "first", string(first.Content))
+		assert.Equal(t, "5", first.Header.Get("content-length"))
+		assert.True(t, strings.HasPrefix(first.Header.Get("WARC-Record-ID"), "<urn:uuid:"))
+		assert.True(t, strings.HasPrefix(first.Header.Get("WARC-Block-Digest"), "sha1:"))
+
+		second, err := r.Next()
+		require.NoError(t, err)
+		assert.Equal(t, "second\r\n\r\nwith blank lines", string(second.Content))
+
+		_, err = r.Next()
+		assert.Equal(t, io.EOF, err, "compress=%v", compress)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawl.warc.gz")
+	w, err := Create(path, "test/1.0")
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRecord(&Record{
+		Header:  Header{{Name: "WARC-Type", Value: TypeMetadata}, {Name: "WARC-Target-URI", Value: "https://example.com/"}},
+		Content: []byte(FieldCrawlStart + ": 2024-01-01T00:00:00Z\r\n" + FieldMaxDepth + ": 2\r\n"),
+	}))
+	require.NoError(t, w.Close())
+
+	records, err := ReadFile(path)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, TypeInfo, records[0].Type())
+	assert.Equal(t, "test/1.0", ParseFields(records[0].Content)["software"])
+
+	replay := NewReplay(records)
+	assert.Equal(t, []string{"https://example.com/"}, replay.StartURLs())
+	depth, ok := replay.MaxDepth("https://example.com/")
+	assert.True(t, ok)
+	assert.Equal(t, 2, depth)
+	_, ok = replay.MaxDepth("https://example.org/")
+	assert.False(t, ok)
+}
+
+func TestReplay(t *testing.T) {
+	response := func(uri, message string) *Record {
+		return &Record{
+			Header: Header{
+				{Name: "WARC-Type", Value: TypeResponse},
+				{Name: "WARC-Target-URI", Value: uri},
+				{Name: "Content-Type", Value: ContentTypeResponse},
+			},
+			Content: []byte(message),
+		}
+	}
+	replay := NewReplay([]*Record{
+		response("https://example.com/", "HTTP/1.1 503 Service Unavailable\r\nContent-Length: 0\r\n\r\n"),
+		response("https://example.com/", "HTTP/1.1 200 OK\r\nContent-Type: text/html\r\nContent-Length: 5\r\n\r\nhello"),
+		response("https://example.com/robots.txt", "HTTP/1.1 200 OK\r\nContent-Length: 40\r\n\r\nUser-agent: *\nCrawl-delay: 10\nDisallow: "),
+	})
+	client := &http.Client{Transport: replay}
+
+	get := func(uri string) (int, string) {
+		resp, err := client.Get(uri)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, _ := get("https://example.com/")
+	assert.Equal(t, http.StatusServiceUnavailable, code, "recorded order")
+	for i := 0; i < 2; i++ {
+		code, body := get("https://example.com/")
+		assert.Equal(t, http.StatusOK, code, "last response repeats")
+		assert.Equal(t, "hello", body)
+	}
+
+	code, _ = get("https://example.com/missing")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	_, robots := get("https://example.com/robots.txt")
+	assert.NotContains(t, robots, "Crawl-delay")
+	assert.Contains(t, robots, "Disallow:")
+}
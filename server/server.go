@@ -10,9 +10,11 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"sync"
 	"sync/atomic"
 
 	"github.com/gorilla/websocket"
+	"github.com/xhad/yes/internal/app"
 	cfgPkg "github.com/xhad/yes/pkg/config"
 	"github.com/xhad/yes/pkg/llm"
 	"github.com/xhad/yes/pkg/processor"
@@ -39,22 +41,40 @@ type WSServer struct {
 	chatEngine  *llm.ChatEngine
 	processor   *processor.Processor
 	vectorStore *store.VectorStore
+	writeMu     sync.Mutex // websocket connections allow a single concurrent writer
+
+	defaultExtractor scraper.ContentExtractor
+	extractorRules   []scraper.ExtractorRule
 }
 
 type Config struct {
-	BaseURL     string
-	DBUrl       string
-	DocsURL     string
-	Model       string
-	MaxDepth    int
-	ChunkSize   int
-	VectorDim   int
-	TableName   string
-	BatchSize   int
-	RateLimit   float64
-	MaxTokens   int
-	Streaming   bool
-	Temperature float64
+	BaseURL        string
+	DBUrl          string
+	DocsURL        string
+	Model          string
+	MaxDepth       int
+	VectorDim      int
+	TableName      string
+	BatchSize      int
+	RateLimit      float64
+	Workers        int
+	MaxQueueSize   int
+	UserAgent      string
+	IgnoreRobots   bool
+	UseSitemaps    bool
+	TrackingParams []string
+	Retry          scraper.RetryPolicy
+	Scope          scraper.CrawlScope
+	HostRateLimits []scraper.HostRateLimit
+	HTTPProfiles   []scraper.HTTPProfile
+	Extractor      string
+	Extractors     []cfgPkg.ExtractorConfig
+	MaxTokens      int
+	Streaming      bool
+	Temperature    float64
+
+	// Chunking and normalization, by default and for each source
+	Processor cfgPkg.ProcessorConfig
 }
 
 func NewWSServer(config Config) (*WSServer, error) {
@@ -68,10 +88,10 @@ func NewWSServer(config Config) (*WSServer, error) {
 		return nil, fmt.Errorf("failed to initialize chat engine: %v", err)
 	}
 
-	processor := processor.NewWithConfig(processor.ProcessorConfig{
-		ChunkSize:    config.ChunkSize,
-		ChunkOverlap: 200,
-	})
+	processor, err := app.NewProcessor(config.Processor, "web")
+	if err != nil {
+		return nil, err
+	}
 
 	vectorStore, err := store.NewWithConfig(store.VectorStoreConfig{
 		ConnString: config.DBUrl,
@@ -83,11 +103,19 @@ func NewWSServer(config Config) (*WSServer, error) {
 		return nil, fmt.Errorf("failed to initialize vector store: %v", err)
 	}
 
+	defaultExtractor, extractorRules, err := app.BuildExtractors(config.Extractor, config.Extractors)
+	if err != nil {
+		vectorStore.Close()
+		return nil, fmt.Errorf("failed to initialize content extractors: %v", err)
+	}
+
 	return &WSServer{
-		config:      config,
-		chatEngine:  chatEngine,
-		processor:   &processor,
-		vectorStore: vectorStore,
+		config:           config,
+		chatEngine:       chatEngine,
+		processor:        processor,
+		vectorStore:      vectorStore,
+		defaultExtractor: defaultExtractor,
+		extractorRules:   extractorRules,
 	}, nil
 }
 
@@ -99,6 +127,10 @@ func (s *WSServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.Close()
 
+	// Cancel in-flight work such as scraping once the client disconnects
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
@@ -112,11 +144,11 @@ func (s *WSServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		go s.handleMessage(conn, msg)
+		go s.handleMessage(ctx, conn, msg)
 	}
 }
 
-func (s *WSServer) handleMessage(conn *websocket.Conn, msg Message) {
+func (s *WSServer) handleMessage(ctx context.Context, conn *websocket.Conn, msg Message) {
 	query := msg.Content
 
 	// Check for URL in the query
@@ -131,12 +163,28 @@ func (s *WSServer) handleMessage(conn *websocket.Conn, msg Message) {
 		// Process URL similar to the original code, but with WebSocket updates
 		var processedCount int32
 		scraper, err := scraper.NewWithConfig(scraper.ScraperConfig{
-			BaseURL:   url,
-			MaxDepth:  s.config.MaxDepth,
-			RateLimit: s.config.RateLimit,
+			BaseURL:          url,
+			MaxDepth:         s.config.MaxDepth,
+			RateLimit:        s.config.RateLimit,
+			Workers:          s.config.Workers,
+			MaxQueueSize:     s.config.MaxQueueSize,
+			UserAgent:        s.config.UserAgent,
+			IgnoreRobots:     s.config.IgnoreRobots,
+			UseSitemaps:      s.config.UseSitemaps,
+			TrackingParams:   s.config.TrackingParams,
+			Retry:            s.config.Retry,
+			Scope:            s.config.Scope,
+			HostRateLimits:   s.config.HostRateLimits,
+			HTTPProfiles:     s.config.HTTPProfiles,
+			PageCache:        s.vectorStore,
+			Extractors:       s.extractorRules,
+			DefaultExtractor: s.defaultExtractor,
 			OnProgress: func(url string) {
-				atomic.AddInt32(&processedCount, 1)
-				s.sendMessage(conn, "progress", fmt.Sprintf("Scraped %d pages", processedCount))
+				count := atomic.AddInt32(&processedCount, 1)
+				s.sendMessage(conn, "progress", fmt.Sprintf("Scraped %d pages", count))
+			},
+			OnSkip: func(url string, reason string) {
+				s.sendMessage(conn, "skipped", fmt.Sprintf("Skipped %s: %s", url, reason))
 			},
 		})
 
@@ -147,7 +195,11 @@ func (s *WSServer) handleMessage(conn *websocket.Conn, msg Message) {
 
 		// Continue with scraping, processing, and storing...
 		// Send progress updates via WebSocket
-		docs, err := scraper.Scrape(url)
+		docs, err := scraper.ScrapeContext(ctx, url)
+		if ctx.Err() != nil {
+			log.Printf("Scraping %s stopped after %d documents: client disconnected", url, len(docs))
+			return
+		}
 		if err != nil {
 			s.sendMessage(conn, "error", fmt.Sprintf("Failed to scrape URL: %v", err))
 			return
@@ -163,7 +215,8 @@ func (s *WSServer) handleMessage(conn *websocket.Conn, msg Message) {
 
 	// Handle regular chat query
 	emb := llm.NewEmbedder()
-	queryArray := []string{query}
+	// Normalize the question like the chunks it is compared with
+	queryArray := []string{s.processor.Normalize(query)}
 
 	embeddings, err := emb.Embed.CreateEmbedding(context.Background(), queryArray)
 	if err != nil {
@@ -207,6 +260,8 @@ func (s *WSServer) sendMessage(conn *websocket.Conn, msgType string, content str
 		Type:    msgType,
 		Content: content,
 	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	if err := conn.WriteJSON(msg); err != nil {
 		log.Printf("Error sending message: %v", err)
 	}
@@ -251,11 +306,19 @@ func parseFlags() Config {
 	flag.StringVar(&config.DocsURL, "docs-url", "", "Documentation URL to scrape")
 	flag.StringVar(&config.Model, "model", "gpt-3.5-turbo", "LLM model to use")
 	flag.IntVar(&config.MaxDepth, "max-depth", 3, "Maximum depth for web scraping")
-	flag.IntVar(&config.ChunkSize, "chunk-size", 1000, "Size of text chunks")
+	flag.IntVar(&config.Processor.ChunkSize, "chunk-size", 512, "Maximum tokens in a text chunk")
+	flag.IntVar(&config.Processor.ChunkOverlap, "chunk-overlap", 64, "Tokens of text repeated between chunks")
+	flag.StringVar(&config.Processor.Chunker, "chunker", "", "Chunking strategy: sentence, fixed, recursive, sentence-window or semantic")
 	flag.IntVar(&config.VectorDim, "vector-dim", 768, "Vector dimension")
 	flag.StringVar(&config.TableName, "table", "documents", "PostgreSQL table name")
 	flag.IntVar(&config.BatchSize, "batch-size", 100, "Batch size for database operations")
 	flag.Float64Var(&config.RateLimit, "rate-limit", 2.0, "Rate limit for web scraping")
+	flag.IntVar(&config.Workers, "workers", 4, "Number of concurrent scraper workers")
+	flag.IntVar(&config.MaxQueueSize, "max-queue-size", 10000, "Maximum number of URLs waiting to be crawled")
+	flag.StringVar(&config.UserAgent, "user-agent", "", "User-Agent for web scraping")
+	flag.BoolVar(&config.IgnoreRobots, "ignore-robots", false, "Ignore robots.txt when scraping")
+	flag.BoolVar(&config.UseSitemaps, "sitemaps", false, "Seed scraping from the site's sitemaps")
+	flag.StringVar(&config.Extractor, "extractor", "", "Content extractor: selectors or readability")
 	flag.IntVar(&config.MaxTokens, "max-tokens", 2000, "Maximum tokens for LLM response")
 	flag.BoolVar(&config.Streaming, "stream", true, "Enable streaming responses")
 	flag.Float64Var(&config.Temperature, "temperature", 0.8, "Set the LLM Temperature")
@@ -278,7 +341,33 @@ func parseFlags() Config {
 		config.BatchSize = cfg.Database.BatchSize
 		config.MaxDepth = cfg.Scraper.MaxDepth
 		config.RateLimit = cfg.Scraper.RateLimit
-		config.ChunkSize = cfg.Processor.ChunkSize
+		config.Workers = cfg.Scraper.Workers
+		config.MaxQueueSize = cfg.Scraper.MaxQueueSize
+		config.UserAgent = cfg.Scraper.UserAgent
+		config.IgnoreRobots = cfg.Scraper.IgnoreRobots
+		config.UseSitemaps = cfg.Scraper.UseSitemaps
+		config.TrackingParams = cfg.Scraper.TrackingParams
+		config.Retry = scraper.RetryPolicy{
+			MaxAttempts: cfg.Scraper.Retry.MaxAttempts,
+			BaseDelay:   cfg.Scraper.Retry.BaseDelay,
+			MaxDelay:    cfg.Scraper.Retry.MaxDelay,
+		}
+		config.Scope = scraper.CrawlScope{
+			Hosts:        cfg.Scraper.Scope.Hosts,
+			PathPrefixes: cfg.Scraper.Scope.PathPrefixes,
+			Include:      cfg.Scraper.Scope.Include,
+			Exclude:      cfg.Scraper.Scope.Exclude,
+		}
+		for _, limit := range cfg.Scraper.HostRateLimits {
+			config.HostRateLimits = append(config.HostRateLimits, scraper.HostRateLimit{
+				Hosts:     limit.Hosts,
+				RateLimit: limit.RateLimit,
+			})
+		}
+		config.HTTPProfiles = app.BuildHTTPProfiles(cfg.Scraper.HTTPProfiles)
+		config.Extractor = cfg.Scraper.Extractor
+		config.Extractors = cfg.Scraper.Extractors
+		config.Processor = cfg.Processor
 		config.Streaming = cfg.UI.Streaming
 		config.Temperature = cfg.LLM.Temperature
 	}